@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// atomicFile is written under a temporary name next to its destination and
+// renamed over it on commit, so that a crash or a concurrent reader never
+// sees it half-written.
+type atomicFile struct {
+	*os.File
+	path string
+	perm os.FileMode
+}
+
+// writePath returns the file a write to path should replace: the target
+// of a symlink (such as a shared entry), or path itself.
+func writePath(path string) string {
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		if target, err := filepath.EvalSymlinks(path); err == nil {
+			return target
+		}
+	}
+	return path
+}
+
+func createAtomic(path string, perm os.FileMode) (*atomicFile, error) {
+	path = writePath(path)
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	return &atomicFile{File: f, path: path, perm: perm}, nil
+}
+
+// commit puts the written file in place.
+func (f *atomicFile) commit() error {
+	err := f.Sync()
+	if err == nil {
+		err = f.Chmod(f.perm)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), f.path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// abort throws the written file away.
+func (f *atomicFile) abort() {
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// writeFileAtomic is os.WriteFile through an atomicFile.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := createAtomic(path, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.abort()
+		return err
+	}
+	return f.commit()
+}
+
+// replaceSymlink points link at target by renaming a new symlink over it,
+// so that link never goes missing.
+func replaceSymlink(target, link string) error {
+	tmp := link + ".tmp-" + strconv.Itoa(os.Getpid())
+	os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		// Windows can't rename over a directory link
+		os.Remove(link)
+		if err := os.Rename(tmp, link); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const auditFileName = "audit.jsonl"
+
+// Settings files whose changes the audit log records, per profile. Other
+// changes, like the shared links, transcripts and sessions that link,
+// prune and session move change, are only recorded as the command that
+// made them.
+var auditedSettingsFiles = []string{
+	"settings.json",
+	"settings.local.json",
+	claudeJSONFile,
+	credentialsFile,
+	profileMetaFile,
+}
+
+// auditRecord is one line of the audit log: a command that changes mcc's
+// or a profile's state, and the settings files it changed.
+type auditRecord struct {
+	Time     time.Time     `json:"time"`
+	User     string        `json:"user,omitempty"`
+	Host     string        `json:"host,omitempty"`
+	Command  string        `json:"command"`
+	Args     []string      `json:"args,omitempty"`
+	Files    []auditChange `json:"files,omitempty"`
+	ExitCode int           `json:"exit_code"`
+}
+
+// auditChange is an audited settings file a command created, changed or
+// removed, with the SHA-256 of its contents before and after. An empty hash
+// means the file didn't exist.
+type auditChange struct {
+	Path   string `json:"path"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// The audit of the running command, finished by finishAudit.
+var pendingAudit *auditRecord
+var pendingAuditHashes map[string]string
+
+func getAuditPath() string {
+	return filepath.Join(getMccDir(), auditFileName)
+}
+
+// isMutation reports whether a command line changes state and so belongs
+// in the audit log. Launching claude is recorded in the launch log instead.
+func isMutation(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	parsed := parseArgs(args[1:], "dry-run", "fix", "force", "prune", "watch", "supervise", "xdg", "remove", "no-switch")
+	switch args[0] {
+	case "new", "create", "add", "delete", "rm", "remove", "sync", "set-key", "unlink", "init", "uninstall", "apply":
+		return true
+	case "link":
+		return len(parsed.pos) > 0
+	case "priority":
+		return len(args) > 1
+	case "mcp":
+		return parsed.arg(0) == "copy" || parsed.arg(0) == "sync"
+	case "session", "sessions":
+		return parsed.arg(0) == "move" || parsed.arg(0) == "copy"
+	case "prune":
+		return !parsed.has("dry-run")
+	case "doctor":
+		return parsed.has("fix")
+	case "home":
+		return parsed.arg(0) == "move"
+	case "group", "groups":
+		return parsed.arg(0) == "create" || parsed.arg(0) == "delete" || parsed.arg(0) == "rm"
+	case "describe":
+		return len(parsed.pos) > 1 || parsed.has("owner") || parsed.has("color")
+	case "tag":
+		return len(parsed.pos) > 1
+	case "template", "templates":
+		return parsed.arg(0) == "save" || parsed.arg(0) == "delete" || parsed.arg(0) == "rm"
+	}
+	return false
+}
+
+// maskArgs hides API keys in a command line: anything that looks like a
+// secret, and the key arguments of set-key and new.
+func maskArgs(args []string) []string {
+	var key string
+	parsed := parseArgs(args[1:])
+	switch args[0] {
+	case "set-key":
+		key = parsed.arg(1)
+	case "new", "create", "add":
+		key = parsed.arg(2)
+	}
+
+	masked := make([]string, len(args))
+	for i, arg := range args {
+		if (key != "" && arg == key) || len(findSecrets([]byte(arg))) > 0 {
+			masked[i] = maskKey(arg)
+		} else {
+			masked[i] = arg
+		}
+	}
+	return masked
+}
+
+func hashFile(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashSettingsFiles hashes config.json and every profile's audited
+// settings files, keyed by path relative to the mcc directory.
+func hashSettingsFiles() map[string]string {
+	hashes := make(map[string]string)
+	add := func(rel, path string) {
+		if hash := hashFile(path); hash != "" {
+			hashes[rel] = hash
+		}
+	}
+	add(configFileName, getConfigPath())
+	profiles, _ := listProfiles()
+	for _, profile := range profiles {
+		for _, name := range auditedSettingsFiles {
+			rel := filepath.Join(profilesDirName, profile, name)
+			add(rel, filepath.Join(getMccDir(), rel))
+		}
+	}
+	return hashes
+}
+
+// startAudit begins auditing the command line when it changes state.
+func startAudit(args []string) {
+	if !isMutation(args) {
+		return
+	}
+	record := &auditRecord{
+		Time:    time.Now(),
+		Command: args[0],
+		Args:    maskArgs(args)[1:],
+	}
+	if u, err := user.Current(); err == nil {
+		record.User = u.Username
+	}
+	record.Host, _ = os.Hostname()
+	pendingAudit = record
+	pendingAuditHashes = hashSettingsFiles()
+}
+
+// finishAudit records which settings files the command changed and appends
+// the audit record. It does nothing when no audit was started.
+func finishAudit(exitCode int) {
+	record := pendingAudit
+	if record == nil {
+		return
+	}
+	pendingAudit = nil
+
+	after := hashSettingsFiles()
+	paths := make(map[string]bool)
+	for path := range pendingAuditHashes {
+		paths[path] = true
+	}
+	for path := range after {
+		paths[path] = true
+	}
+	for path := range paths {
+		if pendingAuditHashes[path] != after[path] {
+			record.Files = append(record.Files, auditChange{
+				Path:   filepath.ToSlash(path),
+				Before: pendingAuditHashes[path],
+				After:  after[path],
+			})
+		}
+	}
+	sort.Slice(record.Files, func(i, j int) bool { return record.Files[i].Path < record.Files[j].Path })
+	record.ExitCode = exitCode
+
+	if err := appendAudit(record); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not write audit log: %v\n", err)
+	}
+}
+
+func appendAudit(record *auditRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(getAuditPath(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// exit ends mcc with the given status, recording the running command in the
+// audit log first. Use it instead of os.Exit once a command has started.
+func exit(code int) {
+	finishAudit(code)
+	os.Exit(code)
+}
+
+func loadAudit() ([]*auditRecord, error) {
+	f, err := os.Open(getAuditPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []*auditRecord
+	err = forEachLine(f, func(line []byte) error {
+		var record auditRecord
+		if len(bytes.TrimSpace(line)) > 0 && json.Unmarshal(line, &record) == nil {
+			records = append(records, &record)
+		}
+		return nil
+	})
+	return records, err
+}
+
+// auditFilter holds the options of mcc audit.
+type auditFilter struct {
+	since   string
+	profile string
+	command string
+	limit   int
+}
+
+// touchesProfile reports whether a record names the profile in its
+// arguments or changed one of its files.
+func (r *auditRecord) touchesProfile(profile string) bool {
+	for _, arg := range r.Args {
+		if arg == profile || strings.Contains(arg, "="+profile) || containsString(strings.Split(arg, ","), profile) {
+			return true
+		}
+	}
+	prefix := profilesDirName + "/" + profile + "/"
+	for _, change := range r.Files {
+		if strings.HasPrefix(change.Path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func shortHash(hash string) string {
+	if hash == "" {
+		return "(none)"
+	}
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+func showAudit(filter auditFilter, asJSON bool) error {
+	start, err := parseSince(filter.since)
+	if err != nil {
+		return err
+	}
+	records, err := loadAudit()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", getAuditPath(), err)
+	}
+
+	// Newest first
+	var shown []*auditRecord
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		if record.Time.Before(start) ||
+			(filter.command != "" && record.Command != filter.command) ||
+			(filter.profile != "" && !record.touchesProfile(filter.profile)) {
+			continue
+		}
+		if filter.limit > 0 && len(shown) == filter.limit {
+			break
+		}
+		shown = append(shown, record)
+	}
+
+	if asJSON {
+		if shown == nil {
+			shown = []*auditRecord{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(shown)
+	}
+	if len(shown) == 0 {
+		fmt.Println("No audit records found")
+		return nil
+	}
+
+	for _, record := range shown {
+		result := "✓"
+		if record.ExitCode != 0 {
+			result = fmt.Sprintf("✗ exit %d", record.ExitCode)
+		}
+		who := record.User
+		if record.Host != "" {
+			who += "@" + record.Host
+		}
+		fmt.Printf("%s  %s  mcc %s  %s\n",
+			record.Time.Local().Format("2006-01-02 15:04:05"), who,
+			strings.TrimSpace(record.Command+" "+strings.Join(record.Args, " ")), result)
+		for _, change := range record.Files {
+			mark := "~"
+			switch {
+			case change.Before == "":
+				mark = "+"
+			case change.After == "":
+				mark = "-"
+			}
+			fmt.Printf("    %s %s  %s → %s\n", mark, change.Path, shortHash(change.Before), shortHash(change.After))
+		}
+	}
+	return nil
+}
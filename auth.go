@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const credentialsFile = ".credentials.json"
+
+// Login states reported by inspectLogin.
+const (
+	loginOK         = "logged in"
+	loginExpired    = "token expired"
+	loginNeeded     = "needs login"
+	loginAPIKey     = "api key"
+	loginKeyMissing = "no api key"
+	loginMetaBroken = "unreadable " + profileMetaFile
+)
+
+// loginStatus describes whether a profile can start claude without logging in.
+type loginStatus struct {
+	State        string
+	Email        string
+	Org          string
+	Subscription string
+	ExpiresAt    time.Time
+	CanRefresh   bool
+	// Masked API key for API-key profiles
+	Key string
+	// Where the credentials were found, for whoami
+	Source string
+}
+
+// maskKey keeps just enough of a key to recognise it.
+func maskKey(key string) string {
+	if len(key) <= 12 {
+		return strings.Repeat("*", len(key))
+	}
+	prefix := key[:3]
+	if strings.HasPrefix(key, "sk-ant-") {
+		prefix = key[:7]
+	}
+	return prefix + "…" + key[len(key)-4:]
+}
+
+func inspectLogin(profile string) *loginStatus {
+	profilePath := filepath.Join(getProfilesDir(), profile)
+	status := &loginStatus{}
+	meta, err := loadProfileMeta(profilePath)
+	if err != nil {
+		status.State = loginMetaBroken
+		status.Source = profileMetaFile
+		return status
+	}
+
+	if meta.Provider != "claude" || meta.hasAPIKey() {
+		status.Source = profileMetaFile
+		switch {
+		case meta.KeyCommand != "":
+			status.State = loginAPIKey
+			status.Key = "from key_command"
+		case meta.KeyEnv != "":
+			status.State = loginAPIKey
+			status.Key = "$" + meta.KeyEnv
+		case meta.APIKey != "":
+			status.State = loginAPIKey
+			status.Key = maskKey(meta.APIKey)
+		default:
+			status.State = loginKeyMissing
+		}
+		return status
+	}
+
+	var account struct {
+		OAuthAccount *struct {
+			Email   string `json:"emailAddress"`
+			OrgName string `json:"organizationName"`
+		} `json:"oauthAccount"`
+		PrimaryAPIKey string `json:"primaryApiKey"`
+	}
+	if data, err := os.ReadFile(filepath.Join(profilePath, claudeJSONFile)); err == nil {
+		json.Unmarshal(data, &account)
+	}
+	if account.OAuthAccount != nil {
+		status.Email = account.OAuthAccount.Email
+		status.Org = account.OAuthAccount.OrgName
+	}
+
+	var creds struct {
+		OAuth *struct {
+			AccessToken      string `json:"accessToken"`
+			RefreshToken     string `json:"refreshToken"`
+			ExpiresAt        int64  `json:"expiresAt"`
+			SubscriptionType string `json:"subscriptionType"`
+		} `json:"claudeAiOauth"`
+	}
+	credsData, credsErr := os.ReadFile(filepath.Join(profilePath, credentialsFile))
+	if credsErr == nil {
+		json.Unmarshal(credsData, &creds)
+	}
+
+	switch {
+	case creds.OAuth != nil && creds.OAuth.AccessToken != "":
+		status.Source = credentialsFile
+		status.Subscription = creds.OAuth.SubscriptionType
+		status.CanRefresh = creds.OAuth.RefreshToken != ""
+		if creds.OAuth.ExpiresAt > 0 {
+			status.ExpiresAt = time.UnixMilli(creds.OAuth.ExpiresAt)
+		}
+		if !status.ExpiresAt.IsZero() && time.Now().After(status.ExpiresAt) && !status.CanRefresh {
+			status.State = loginExpired
+		} else {
+			status.State = loginOK
+		}
+	case account.PrimaryAPIKey != "":
+		status.State = loginAPIKey
+		status.Key = maskKey(account.PrimaryAPIKey)
+		status.Source = claudeJSONFile
+	case account.OAuthAccount != nil && runtime.GOOS == "darwin":
+		// claude keeps OAuth tokens in the macOS keychain, not on disk
+		status.State = loginOK
+		status.Source = "keychain"
+	default:
+		status.State = loginNeeded
+	}
+	return status
+}
+
+// summary is the one-line form shown by mcc status.
+func (s *loginStatus) summary() string {
+	switch s.State {
+	case loginAPIKey:
+		return "key " + s.Key
+	case loginKeyMissing:
+		return "⚠️  no API key (mcc set-key)"
+	case loginNeeded:
+		return "⚠️  needs login"
+	case loginExpired:
+		return "⚠️  token expired, needs login"
+	}
+
+	var parts []string
+	if s.Email != "" {
+		parts = append(parts, s.Email)
+	} else {
+		parts = append(parts, s.State)
+	}
+	if s.Org != "" {
+		parts = append(parts, "("+s.Org+")")
+	}
+	if !s.ExpiresAt.IsZero() && time.Now().After(s.ExpiresAt) {
+		parts = append(parts, "token refreshes on next launch")
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatUntil describes how far t is from now, e.g. "in 3h20m" or "2d ago".
+func formatUntil(t time.Time) string {
+	d := time.Until(t)
+	suffix := ""
+	prefix := "in "
+	if d < 0 {
+		d = -d
+		prefix = ""
+		suffix = " ago"
+	}
+	var text string
+	switch {
+	case d >= 48*time.Hour:
+		text = fmt.Sprintf("%dd", int(d.Hours()/24))
+	case d >= time.Hour:
+		text = fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		text = fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return prefix + text + suffix
+}
+
+func showWhoami(profile string) error {
+	if profile == "" {
+		config, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		profile = config.CurrentProfile
+	}
+	if !profileExists(profile) {
+		return profileNotFound(profile)
+	}
+
+	meta, err := loadProfileMeta(filepath.Join(getProfilesDir(), profile))
+	if err != nil {
+		return err
+	}
+	status := inspectLogin(profile)
+
+	fmt.Printf("Profile:      %s\n", profile)
+	fmt.Printf("Provider:     %s\n", meta.Provider)
+	fmt.Printf("Status:       %s\n", status.State)
+	if status.Email != "" {
+		fmt.Printf("Account:      %s\n", status.Email)
+	}
+	if status.Org != "" {
+		fmt.Printf("Organization: %s\n", status.Org)
+	}
+	if status.Subscription != "" {
+		fmt.Printf("Subscription: %s\n", status.Subscription)
+	}
+	if !status.ExpiresAt.IsZero() {
+		verb, note := "expires", ""
+		if time.Now().After(status.ExpiresAt) {
+			verb = "expired"
+			if status.CanRefresh {
+				note = " (refreshed on next launch)"
+			}
+		}
+		fmt.Printf("Token:        %s %s, %s%s\n", verb, status.ExpiresAt.Local().Format("2006-01-02 15:04"), formatUntil(status.ExpiresAt), note)
+	}
+	if status.Key != "" {
+		fmt.Printf("API key:      %s\n", status.Key)
+	}
+	if status.Source != "" {
+		fmt.Printf("Credentials:  %s\n", status.Source)
+	}
+
+	switch status.State {
+	case loginNeeded, loginExpired:
+		fmt.Printf("\nRun 'mcc run %s' and log in with /login.\n", profile)
+	case loginKeyMissing:
+		fmt.Printf("\nSet one with 'mcc set-key %s <api-key>'.\n", profile)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInspectLoginKeys(t *testing.T) {
+	key := "sk-" + "ant-api03-" + "abcdefgh1234"
+	tests := []struct {
+		name  string
+		meta  ProfileMeta
+		state string
+		key   string
+	}{
+		{"claude login", ProfileMeta{Provider: "claude"}, loginNeeded, ""},
+		{"claude with stored key", ProfileMeta{Provider: "claude", APIKey: key}, loginAPIKey, maskKey(key)},
+		{"claude with key_env", ProfileMeta{Provider: "claude", KeyEnv: "MY_KEY"}, loginAPIKey, "$MY_KEY"},
+		{"claude with key_command", ProfileMeta{Provider: "claude", KeyCommand: "pass x"}, loginAPIKey, "from key_command"},
+		{"kimi without key", ProfileMeta{Provider: "kimi"}, loginKeyMissing, ""},
+	}
+	useTempHome(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profilePath := filepath.Join(getProfilesDir(), "p")
+			if err := os.MkdirAll(profilePath, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := saveProfileMeta(profilePath, &tt.meta); err != nil {
+				t.Fatal(err)
+			}
+			status := inspectLogin("p")
+			if status.State != tt.state || status.Key != tt.key {
+				t.Errorf("inspectLogin = %q, key %q; want %q, key %q", status.State, status.Key, tt.state, tt.key)
+			}
+		})
+	}
+}
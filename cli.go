@@ -0,0 +1,136 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// cmdArgs holds the positional arguments and --flag values of a subcommand.
+type cmdArgs struct {
+	pos   []string
+	flags map[string]string
+}
+
+// parseArgs splits args into positional arguments and flags. Flags may be
+// written as --name value or --name=value and may appear anywhere after the
+// subcommand. Names listed in boolFlags never consume a following value.
+func parseArgs(args []string, boolFlags ...string) *cmdArgs {
+	isBool := func(name string) bool {
+		for _, b := range boolFlags {
+			if b == name {
+				return true
+			}
+		}
+		return false
+	}
+
+	parsed := &cmdArgs{flags: make(map[string]string)}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			parsed.pos = append(parsed.pos, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "--") || len(arg) == 2 {
+			parsed.pos = append(parsed.pos, arg)
+			continue
+		}
+		name := strings.TrimPrefix(arg, "--")
+		if eq := strings.Index(name, "="); eq >= 0 {
+			parsed.flags[name[:eq]] = name[eq+1:]
+			continue
+		}
+		if isBool(name) || i+1 >= len(args) {
+			parsed.flags[name] = "true"
+			continue
+		}
+		parsed.flags[name] = args[i+1]
+		i++
+	}
+	return parsed
+}
+
+func (a *cmdArgs) has(name string) bool {
+	_, ok := a.flags[name]
+	return ok
+}
+
+func (a *cmdArgs) get(name string) string {
+	return a.flags[name]
+}
+
+// list returns a comma-separated flag value as a slice.
+func (a *cmdArgs) list(name string) []string {
+	value := a.flags[name]
+	if value == "" {
+		return nil
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// count returns a non-negative number flag, or def when it isn't given.
+func (a *cmdArgs) count(name string, def int) (int, error) {
+	value, ok := a.flags[name]
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, usageError("invalid --%s value '%s'", name, value)
+	}
+	return n, nil
+}
+
+func (a *cmdArgs) arg(i int) string {
+	if i < len(a.pos) {
+		return a.pos[i]
+	}
+	return ""
+}
+
+// envEnabled reports whether an environment variable is set to something
+// other than empty, 0 or false.
+func envEnabled(name string) bool {
+	value := strings.ToLower(os.Getenv(name))
+	return value != "" && value != "0" && value != "false"
+}
+
+// parseGlobalFlags applies the flags every command accepts, --quiet/-q and
+// --home <dir>, and returns the command line without them. Arguments after
+// "--" are left alone.
+func parseGlobalFlags(args []string) ([]string, error) {
+	quiet = envEnabled("MCC_QUIET")
+
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return append(rest, args[i:]...), nil
+		case arg == "--quiet" || arg == "-q":
+			quiet = true
+		case arg == "--home" || strings.HasPrefix(arg, "--home="):
+			dir := strings.TrimPrefix(strings.TrimPrefix(arg, "--home"), "=")
+			if arg == "--home" {
+				if i+1 >= len(args) {
+					return nil, usageError("--home needs a directory")
+				}
+				i++
+				dir = args[i]
+			}
+			if err := setMccHome(dir); err != nil {
+				return nil, err
+			}
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest, nil
+}
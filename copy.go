@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Maximum number of failures spelled out in a copy error.
+const maxReportedFailures = 10
+
+// copyReport collects the outcome of a copy that keeps going past errors.
+type copyReport struct {
+	copied   int
+	excluded int
+	special  []string
+	failures []copyFailure
+}
+
+// copyAction is what a copyFilter decides for an entry.
+type copyAction int
+
+const (
+	copyInclude  copyAction = iota
+	copyExclude             // leave it out, and everything below it for directories
+	copyRedacted            // copy a regular file with secrets masked
+)
+
+// copyFilter is called by copyTree for every entry below the copy root.
+type copyFilter func(rel string, d fs.DirEntry) copyAction
+
+type copyFailure struct {
+	path string
+	err  error
+}
+
+func (r *copyReport) fail(path string, err error) {
+	r.failures = append(r.failures, copyFailure{path: path, err: err})
+}
+
+// warn prints the special files that were left out of the copy.
+func (r *copyReport) warn() {
+	for _, path := range r.special {
+		fmt.Fprintf(os.Stderr, "⚠️  Skipped special file: %s\n", path)
+	}
+}
+
+// err returns nil when everything was copied, or an error listing the
+// entries that failed.
+func (r *copyReport) err() error {
+	if len(r.failures) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d entry(s) could not be copied", len(r.failures))
+	for i, failure := range r.failures {
+		if i == maxReportedFailures {
+			fmt.Fprintf(&b, "\n  ... and %d more", len(r.failures)-i)
+			break
+		}
+		fmt.Fprintf(&b, "\n  %s: %v", failure.path, failure.err)
+	}
+	return fmt.Errorf("%s", b.String())
+}
+
+// copyTree copies src (a directory, file or symlink) to dst. Symlinks are
+// recreated as symlinks, modes and modification times are kept, files are
+// streamed, and sockets, FIFOs and devices are skipped. A failing entry is
+// recorded in the report and the walk continues. filter, if set, decides
+// what happens to each entry below src.
+func copyTree(src, dst string, filter copyFilter) *copyReport {
+	report := &copyReport{}
+
+	// Directory modes and times are applied after their contents are written
+	type dirStamp struct {
+		path string
+		info fs.FileInfo
+	}
+	var dirs []dirStamp
+
+	walkErr := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			report.fail(path, err)
+			if d != nil && d.IsDir() && path != src {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			report.fail(path, err)
+			return nil
+		}
+		action := copyInclude
+		// The root is only filtered when it is a single file
+		if filter != nil && (rel != "." || !d.IsDir()) {
+			action = filter(rel, d)
+		}
+		if action == copyExclude {
+			report.excluded++
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			report.fail(path, err)
+			return nil
+		}
+		dstPath := filepath.Join(dst, rel)
+
+		switch mode := info.Mode(); {
+		case mode.IsDir():
+			if err := os.MkdirAll(dstPath, mode.Perm()|0700); err != nil {
+				report.fail(path, err)
+				return filepath.SkipDir
+			}
+			dirs = append(dirs, dirStamp{path: dstPath, info: info})
+		case mode&fs.ModeSymlink != 0:
+			if err := copySymlink(path, dstPath); err != nil {
+				report.fail(path, err)
+				return nil
+			}
+			report.copied++
+		case mode.IsRegular():
+			write := copyFile
+			if action == copyRedacted {
+				write = redactFile
+			}
+			if err := write(path, dstPath, info); err != nil {
+				report.fail(path, err)
+				return nil
+			}
+			report.copied++
+		default:
+			report.special = append(report.special, path)
+		}
+		return nil
+	})
+	if walkErr != nil {
+		report.fail(src, walkErr)
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		dir := dirs[i]
+		if err := os.Chmod(dir.path, dir.info.Mode().Perm()); err != nil {
+			report.fail(dir.path, err)
+			continue
+		}
+		os.Chtimes(dir.path, dir.info.ModTime(), dir.info.ModTime())
+	}
+
+	return report
+}
+
+// copyFile streams a regular file to dst, keeping its mode and mtime.
+func copyFile(src, dst string, info fs.FileInfo) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := createAtomic(dst, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.abort()
+		return err
+	}
+	if err := out.commit(); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// copySymlink recreates the symlink at src as dst, replacing whatever
+// non-directory entry is already there.
+func copySymlink(src, dst string) error {
+	target, err := os.Readlink(src)
+	if err != nil {
+		return err
+	}
+	if existing, err := os.Lstat(dst); err == nil {
+		if existing.IsDir() {
+			return fmt.Errorf("cannot replace directory %s with a symlink", dst)
+		}
+		if current, err := os.Readlink(dst); err == nil && current == target {
+			return nil
+		}
+		if err := os.Remove(dst); err != nil {
+			return err
+		}
+	}
+	return os.Symlink(target, dst)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// costDay is the estimated spend of a profile on one day.
+type costDay struct {
+	Day  string  `json:"day"`
+	Cost float64 `json:"cost_usd"`
+	tokenUsage
+}
+
+// profileCost is the estimated spend of a profile.
+type profileCost struct {
+	Profile string `json:"profile"`
+	// Provider tag as shown by mcc status
+	Provider string `json:"provider"`
+	// Billing is "api-key" for pay-per-token profiles, "subscription" for
+	// claude logins whose cost is only an API-equivalent estimate
+	Billing  string     `json:"billing"`
+	Cost     float64    `json:"cost_usd"`
+	Days     []*costDay `json:"days"`
+	Unpriced []string   `json:"unpriced_models,omitempty"`
+}
+
+func profileBilling(meta *ProfileMeta) string {
+	if meta.Provider != "claude" || meta.hasAPIKey() {
+		return "api-key"
+	}
+	return "subscription"
+}
+
+func collectCost(profile string, since time.Time, overrides map[string]map[string]modelPrice) (*profileCost, error) {
+	meta, err := loadProfileMeta(filepath.Join(getProfilesDir(), profile))
+	if err != nil {
+		return nil, err
+	}
+	result := &profileCost{
+		Profile:  profile,
+		Provider: meta.Provider,
+		Billing:  profileBilling(meta),
+	}
+
+	days := make(map[string]*costDay)
+	unpriced := make(map[string]bool)
+	err = scanTranscripts(profile, since, func(entry transcriptEntry) {
+		if entry.role != "assistant" {
+			return
+		}
+		dayKey := entry.timestamp.Local().Format("2006-01-02")
+		day, ok := days[dayKey]
+		if !ok {
+			day = &costDay{Day: dayKey}
+			days[dayKey] = day
+		}
+		day.tokenUsage.add(entry.usage)
+
+		price, ok := lookupPrice(overrides, meta.Provider, entry.model)
+		if !ok {
+			if entry.model != "" && entry.model != "<synthetic>" {
+				unpriced[entry.model] = true
+			}
+			return
+		}
+		cost := price.cost(entry.usage)
+		day.Cost += cost
+		result.Cost += cost
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcripts of profile '%s': %w", profile, err)
+	}
+
+	for _, day := range days {
+		result.Days = append(result.Days, day)
+	}
+	sort.Slice(result.Days, func(i, j int) bool { return result.Days[i].Day < result.Days[j].Day })
+	for model := range unpriced {
+		result.Unpriced = append(result.Unpriced, model)
+	}
+	sort.Strings(result.Unpriced)
+	return result, nil
+}
+
+func showCost(profile string, sinceValue string, daily bool, asJSON bool) error {
+	since, err := parseSince(sinceValue)
+	if err != nil {
+		return err
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	var profiles []string
+	if profile != "" {
+		profiles, err = resolveProfiles([]string{profile})
+	} else {
+		profiles, err = listProfiles()
+	}
+	if err != nil {
+		return err
+	}
+
+	var all []*profileCost
+	for _, p := range profiles {
+		cost, err := collectCost(p, since, config.Pricing)
+		if err != nil {
+			return err
+		}
+		all = append(all, cost)
+	}
+
+	if asJSON {
+		data, err := json.MarshalIndent(all, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if since.IsZero() {
+		fmt.Println("Estimated cost from all transcripts")
+	} else {
+		fmt.Printf("Estimated cost since %s\n", since.Format("2006-01-02 15:04"))
+	}
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROFILE\tPROVIDER\tBILLING\tINPUT\tOUTPUT\tCOST")
+	var apiTotal float64
+	for _, c := range all {
+		var total tokenUsage
+		for _, day := range c.Days {
+			total.add(day.tokenUsage)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t$%.2f\n", c.Profile, c.Provider, c.Billing,
+			formatTokens(total.InputTokens+total.CacheCreationInputTokens+total.CacheReadInputTokens),
+			formatTokens(total.OutputTokens), c.Cost)
+		if daily {
+			for _, day := range c.Days {
+				fmt.Fprintf(w, "  %s\t\t\t%s\t%s\t$%.2f\n", day.Day,
+					formatTokens(day.InputTokens+day.CacheCreationInputTokens+day.CacheReadInputTokens),
+					formatTokens(day.OutputTokens), day.Cost)
+			}
+		}
+		if c.Billing == "api-key" {
+			apiTotal += c.Cost
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Printf("API-key spend: $%.2f\n", apiTotal)
+	fmt.Println("Subscription profiles show the API-equivalent value of their usage.")
+	for _, c := range all {
+		if len(c.Unpriced) > 0 {
+			fmt.Fprintf(os.Stderr, "⚠️  %s: no price for %s (add it under \"pricing\" in %s)\n",
+				c.Profile, strings.Join(c.Unpriced, ", "), getConfigPath())
+		}
+	}
+	return nil
+}
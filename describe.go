@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Colors a profile can be given besides #rgb and #rrggbb, the names of the
+// ANSI terminal colors.
+var profileColors = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+func validateColor(color string) error {
+	if color == "" || hexColor.MatchString(color) || containsString(profileColors, color) {
+		return nil
+	}
+	return usageError("invalid color '%s': use #rrggbb or one of %s", color, strings.Join(profileColors, ", "))
+}
+
+func validateTag(tag string) error {
+	if tag == "" || strings.ContainsAny(tag, ", \t\n") {
+		return usageError("invalid tag '%s': tags can't be empty or contain spaces or commas", tag)
+	}
+	return nil
+}
+
+func (m *ProfileMeta) hasTag(tag string) bool {
+	return containsString(m.Tags, tag)
+}
+
+// profileDetails is what listings show about a profile after its name: the
+// description, tags, owner and color that are set.
+func profileDetails(meta *ProfileMeta) string {
+	var parts []string
+	if meta.Description != "" {
+		parts = append(parts, meta.Description)
+	}
+	if len(meta.Tags) > 0 {
+		parts = append(parts, "#"+strings.Join(meta.Tags, " #"))
+	}
+	if meta.Owner != "" {
+		parts = append(parts, "@"+meta.Owner)
+	}
+	if meta.Color != "" {
+		parts = append(parts, "color "+meta.Color)
+	}
+	return strings.Join(parts, "  ")
+}
+
+// describeProfile shows a profile's description, tags, owner and color, or
+// changes the ones given. A nil argument leaves that field as it is.
+func describeProfile(name string, description, owner, color *string) error {
+	if !profileExists(name) {
+		return profileNotFound(name)
+	}
+	profilePath := filepath.Join(getProfilesDir(), name)
+	meta, err := loadProfileMeta(profilePath)
+	if err != nil {
+		return err
+	}
+
+	if description == nil && owner == nil && color == nil {
+		fmt.Printf("Profile:      %s\n", name)
+		fmt.Printf("Provider:     %s\n", meta.Provider)
+		for _, field := range [][2]string{
+			{"Description", meta.Description},
+			{"Tags", strings.Join(meta.Tags, ", ")},
+			{"Owner", meta.Owner},
+			{"Color", meta.Color},
+		} {
+			if field[1] == "" {
+				field[1] = "-"
+			}
+			fmt.Printf("%-13s %s\n", field[0]+":", field[1])
+		}
+		return nil
+	}
+
+	if color != nil {
+		if err := validateColor(*color); err != nil {
+			return err
+		}
+		meta.Color = *color
+	}
+	if description != nil {
+		meta.Description = *description
+	}
+	if owner != nil {
+		meta.Owner = *owner
+	}
+	if err := saveProfileMeta(profilePath, meta); err != nil {
+		return fmt.Errorf("failed to save profile metadata: %w", err)
+	}
+	fmt.Printf("✓ Updated profile: %s\n", name)
+	if details := profileDetails(meta); details != "" {
+		fmt.Printf("  %s\n", details)
+	}
+	return nil
+}
+
+// tagProfile adds tags to a profile, or removes them, and prints the tags
+// it ends up with.
+func tagProfile(name string, tags []string, remove bool) error {
+	if !profileExists(name) {
+		return profileNotFound(name)
+	}
+	profilePath := filepath.Join(getProfilesDir(), name)
+	meta, err := loadProfileMeta(profilePath)
+	if err != nil {
+		return err
+	}
+
+	if len(tags) > 0 {
+		for _, tag := range tags {
+			if err := validateTag(tag); err != nil {
+				return err
+			}
+		}
+		var kept []string
+		for _, tag := range meta.Tags {
+			if !remove || !containsString(tags, tag) {
+				kept = append(kept, tag)
+			}
+		}
+		if !remove {
+			for _, tag := range tags {
+				if !containsString(kept, tag) {
+					kept = append(kept, tag)
+				}
+			}
+		}
+		sort.Strings(kept)
+		meta.Tags = kept
+		if err := saveProfileMeta(profilePath, meta); err != nil {
+			return fmt.Errorf("failed to save profile metadata: %w", err)
+		}
+	}
+
+	if len(meta.Tags) == 0 {
+		fmt.Printf("Profile %s has no tags\n", name)
+		return nil
+	}
+	fmt.Printf("%s: %s\n", name, strings.Join(meta.Tags, ", "))
+	return nil
+}
+
+// profilesWithTag keeps the profiles that have a tag. Profiles whose
+// metadata can't be read are reported and left out.
+func profilesWithTag(profiles []string, tag string) []string {
+	var tagged []string
+	for _, profile := range profiles {
+		meta, err := loadProfileMeta(filepath.Join(getProfilesDir(), profile))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+			continue
+		}
+		if meta.hasTag(tag) {
+			tagged = append(tagged, profile)
+		}
+	}
+	return tagged
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// doctorIssue is a single problem found by 'mcc doctor'. fix, when set,
+// repairs it for 'mcc doctor --fix'.
+type doctorIssue struct {
+	problem string
+	hint    string
+	fix     func() error
+}
+
+// doctorCheck is one of the checks 'mcc doctor' runs. run returns a short
+// detail shown next to a passing check, and the problems found.
+type doctorCheck struct {
+	name string
+	run  func() (string, []doctorIssue)
+}
+
+// Known providers, see providerBaseURLs.
+var knownProviders = []string{"claude", "kimi"}
+
+// Files holding keys or tokens, which should only be readable by their owner.
+var keyFiles = []string{credentialsFile, claudeJSONFile, profileMetaFile}
+
+func checkClaudeBinary() (string, []doctorIssue) {
+	path, err := exec.LookPath("claude")
+	if err != nil {
+		return "", []doctorIssue{{
+			problem: "claude is not on PATH",
+			hint:    "install it with: npm install -g @anthropic-ai/claude-code",
+		}}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		return "", []doctorIssue{{
+			problem: fmt.Sprintf("%s --version failed: %v", path, err),
+			hint:    "reinstall claude: npm install -g @anthropic-ai/claude-code",
+		}}
+	}
+	version := strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+	return fmt.Sprintf("%s, %s", version, path), nil
+}
+
+func checkShellRC() (string, []doctorIssue) {
+	expected := getCurrentLink()
+	if os.Getenv("CLAUDE_CONFIG_DIR") == expected {
+		return "CLAUDE_CONFIG_DIR=" + shortenHome(expected), nil
+	}
+
+	if shellBlockInstalled() {
+		return "", []doctorIssue{{
+			problem: "mcc init has set up your shell, but CLAUDE_CONFIG_DIR isn't set in this one",
+			hint:    "open a new terminal",
+		}}
+	}
+	for _, path := range shellRCFiles() {
+		data, err := os.ReadFile(path)
+		if err == nil && strings.Contains(string(data), "CLAUDE_CONFIG_DIR") {
+			return "", []doctorIssue{{
+				problem: fmt.Sprintf("CLAUDE_CONFIG_DIR is set in %s but not in this shell", shortenHome(path)),
+				hint:    fmt.Sprintf("check that it is %s, or replace the line with mcc init", expected),
+			}}
+		}
+	}
+	return "", []doctorIssue{{
+		problem: "CLAUDE_CONFIG_DIR is not set, so plain 'claude' ignores mcc's profiles",
+		hint:    "mcc init",
+		fix:     func() error { return initShell("") },
+	}}
+}
+
+// relinkCurrent points the current symlink at the configured profile, or at
+// the default profile when that is gone too.
+func relinkCurrent() error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	name := config.CurrentProfile
+	if !profileExists(name) {
+		name = defaultProfile
+	}
+	return switchProfile(name, false)
+}
+
+func checkCurrentLink() (string, []doctorIssue) {
+	link := getCurrentLink()
+	info, err := os.Lstat(link)
+	if err != nil {
+		return "", []doctorIssue{{problem: fmt.Sprintf("%s is missing", link), fix: relinkCurrent}}
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return "", []doctorIssue{{
+			problem: fmt.Sprintf("%s is not a symlink", link),
+			hint:    "move it out of the way; mcc recreates it on the next run",
+		}}
+	}
+	target, _ := os.Readlink(link)
+	if _, err := os.Stat(target); err != nil {
+		return "", []doctorIssue{{
+			problem: fmt.Sprintf("current points at %s, which doesn't exist", target),
+			hint:    "switch profiles with mcc run <name>",
+			fix:     relinkCurrent,
+		}}
+	}
+	return "→ " + filepath.Base(target), nil
+}
+
+func checkConfig() (string, []doctorIssue) {
+	config, err := loadConfig()
+	var corrupt *corruptFileError
+	if errors.As(err, &corrupt) {
+		path := getConfigPath()
+		return "", []doctorIssue{{
+			problem: fmt.Sprintf("%s is corrupt: %s", path, corrupt.detail),
+			hint:    "fix or remove the file; mcc recreates it",
+			fix:     func() error { return os.Rename(path, path+".invalid") },
+		}}
+	}
+	if err != nil {
+		return "", []doctorIssue{{
+			problem: fmt.Sprintf("cannot read %s: %v", getConfigPath(), err),
+			hint:    "fix or remove the file; mcc recreates it",
+		}}
+	}
+
+	var issues []doctorIssue
+	if !profileExists(config.CurrentProfile) {
+		issues = append(issues, doctorIssue{
+			problem: fmt.Sprintf("current profile '%s' doesn't exist", config.CurrentProfile),
+			hint:    fmt.Sprintf("mcc run %s", defaultProfile),
+			fix:     func() error { return switchProfile(defaultProfile, false) },
+		})
+	}
+	var missing []string
+	for _, name := range config.Priority {
+		if !profileExists(name) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		issues = append(issues, doctorIssue{
+			problem: fmt.Sprintf("priority lists missing profile(s): %s", strings.Join(missing, ", ")),
+			hint:    "set it again with mcc priority <name>...",
+			fix: func() error {
+				config, err := loadConfig()
+				if err != nil {
+					return err
+				}
+				var kept []string
+				for _, name := range config.Priority {
+					if profileExists(name) {
+						kept = append(kept, name)
+					}
+				}
+				config.Priority = kept
+				return saveConfig(config)
+			},
+		})
+	}
+	groups := make([]string, 0, len(config.Groups))
+	for group := range config.Groups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		var missing []string
+		for _, name := range config.Groups[group] {
+			if !profileExists(name) {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+		issues = append(issues, doctorIssue{
+			problem: fmt.Sprintf("group '%s' lists missing profile(s): %s", group, strings.Join(missing, ", ")),
+			hint:    fmt.Sprintf("create it again with mcc group create %s <name>... --force", group),
+			fix: func() error {
+				config, err := loadConfig()
+				if err != nil {
+					return err
+				}
+				var kept []string
+				for _, name := range config.Groups[group] {
+					if profileExists(name) {
+						kept = append(kept, name)
+					}
+				}
+				if len(kept) == 0 {
+					delete(config.Groups, group)
+				} else {
+					config.Groups[group] = kept
+				}
+				return saveConfig(config)
+			},
+		})
+	}
+	return "", issues
+}
+
+func checkProfileMeta() (string, []doctorIssue) {
+	profiles, err := listProfiles()
+	if err != nil {
+		return "", []doctorIssue{{problem: fmt.Sprintf("cannot list profiles: %v", err)}}
+	}
+
+	var issues []doctorIssue
+	for _, profile := range profiles {
+		path := filepath.Join(getProfilesDir(), profile, profileMetaFile)
+		meta, err := loadProfileMeta(filepath.Dir(path))
+		var corrupt *corruptFileError
+		switch {
+		case errors.As(err, &corrupt):
+			aside := path + ".invalid"
+			issues = append(issues, doctorIssue{
+				problem: fmt.Sprintf("profile '%s': %s is corrupt: %s", profile, profileMetaFile, corrupt.detail),
+				hint:    fmt.Sprintf("fix the file, or move it aside and set the key again with mcc set-key %s <key>", profile),
+				fix:     func() error { return os.Rename(path, aside) },
+			})
+			continue
+		case errors.Is(err, errNewerSchema):
+			issues = append(issues, doctorIssue{
+				problem: fmt.Sprintf("profile '%s': %s %v", profile, profileMetaFile, errNewerSchema),
+				hint:    "upgrade mcc",
+			})
+			continue
+		case err != nil:
+			issues = append(issues, doctorIssue{
+				problem: fmt.Sprintf("profile '%s': cannot read %s: %v", profile, profileMetaFile, err),
+				hint:    "check the file's owner and permissions",
+			})
+			continue
+		}
+
+		if !containsString(knownProviders, meta.Provider) {
+			issues = append(issues, doctorIssue{
+				problem: fmt.Sprintf("profile '%s': unknown provider '%s'", profile, meta.Provider),
+				hint:    fmt.Sprintf("use one of: %s", strings.Join(knownProviders, ", ")),
+			})
+		}
+		if meta.Provider != "claude" && !meta.hasAPIKey() {
+			issues = append(issues, doctorIssue{
+				problem: fmt.Sprintf("profile '%s': %s provider without an API key", profile, meta.Provider),
+				hint:    fmt.Sprintf("mcc set-key %s <api-key>", profile),
+			})
+		}
+	}
+	return "", issues
+}
+
+func checkKeyPermissions() (string, []doctorIssue) {
+	if runtime.GOOS == "windows" {
+		return "not checked on Windows", nil
+	}
+	profiles, err := listProfiles()
+	if err != nil {
+		return "", []doctorIssue{{problem: fmt.Sprintf("cannot list profiles: %v", err)}}
+	}
+
+	var issues []doctorIssue
+	for _, profile := range profiles {
+		for _, name := range keyFiles {
+			path := filepath.Join(getProfilesDir(), profile, name)
+			info, err := os.Stat(path)
+			if err != nil || info.Mode().Perm()&0077 == 0 {
+				continue
+			}
+			issues = append(issues, doctorIssue{
+				problem: fmt.Sprintf("profile '%s': %s is readable by others (%04o)", profile, name, info.Mode().Perm()),
+				hint:    fmt.Sprintf("chmod 600 %s", path),
+				fix:     func() error { return os.Chmod(path, 0600) },
+			})
+		}
+	}
+	return "", issues
+}
+
+// checkOrphans looks for data mcc left behind: shared entries no profile
+// links to any more, and copies set aside by mcc link.
+func checkOrphans() (string, []doctorIssue) {
+	profiles, err := listProfiles()
+	if err != nil {
+		return "", []doctorIssue{{problem: fmt.Sprintf("cannot list profiles: %v", err)}}
+	}
+
+	var issues []doctorIssue
+	shared, err := os.ReadDir(getSharedDir())
+	if err != nil && !os.IsNotExist(err) {
+		return "", []doctorIssue{{problem: fmt.Sprintf("cannot read %s: %v", getSharedDir(), err)}}
+	}
+	for _, entry := range shared {
+		used := false
+		for _, profile := range profiles {
+			if isSharedLink(filepath.Join(getProfilesDir(), profile), entry.Name()) {
+				used = true
+				break
+			}
+		}
+		if !used {
+			issues = append(issues, doctorIssue{
+				problem: fmt.Sprintf("shared entry '%s' isn't linked from any profile", entry.Name()),
+				hint:    fmt.Sprintf("link it with mcc link %s, or delete %s", entry.Name(), filepath.Join(getSharedDir(), entry.Name())),
+			})
+		}
+	}
+
+	for _, profile := range profiles {
+		profilePath := filepath.Join(getProfilesDir(), profile)
+		entries, err := os.ReadDir(profilePath)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if strings.HasSuffix(entry.Name(), linkBackupSuffix) {
+				issues = append(issues, doctorIssue{
+					problem: fmt.Sprintf("profile '%s': %s was set aside by mcc link", profile, entry.Name()),
+					hint:    fmt.Sprintf("merge what you need into the shared entry, then delete %s", filepath.Join(profilePath, entry.Name())),
+				})
+			}
+		}
+	}
+	return "", issues
+}
+
+func checkSharedLinks() (string, []doctorIssue) {
+	broken, err := findBrokenSharedLinks()
+	if err != nil {
+		return "", []doctorIssue{{problem: fmt.Sprintf("cannot inspect shared links: %v", err)}}
+	}
+	var issues []doctorIssue
+	for _, link := range broken {
+		path := filepath.Join(getProfilesDir(), link.profile, link.entry)
+		issues = append(issues, doctorIssue{
+			problem: fmt.Sprintf("profile '%s': %s links to a missing shared entry", link.profile, link.entry),
+			hint:    fmt.Sprintf("mcc unlink %s --profiles %s, or recreate it with mcc link %s", link.entry, link.profile, link.entry),
+			fix:     func() error { return os.Remove(path) },
+		})
+	}
+	return "", issues
+}
+
+// runDoctor runs every check and, with fix set, repairs what it can.
+func runDoctor(fix bool) error {
+	checks := []doctorCheck{
+		{"claude binary", checkClaudeBinary},
+		{"Shell configuration", checkShellRC},
+		{"Current profile link", checkCurrentLink},
+		{"Config", checkConfig},
+		{"Profile metadata", checkProfileMeta},
+		{"Key file permissions", checkKeyPermissions},
+		{"Leftover files", checkOrphans},
+		{"Shared links", checkSharedLinks},
+	}
+
+	remaining, fixable := 0, 0
+	for _, check := range checks {
+		detail, issues := check.run()
+		if len(issues) == 0 {
+			if detail != "" {
+				fmt.Printf("✓ %s (%s)\n", check.name, detail)
+			} else {
+				fmt.Printf("✓ %s\n", check.name)
+			}
+			continue
+		}
+		fmt.Printf("✗ %s\n", check.name)
+		for _, issue := range issues {
+			fmt.Printf("    %s\n", issue.problem)
+			if fix && issue.fix != nil {
+				if err := issue.fix(); err != nil {
+					fmt.Printf("    ✗ fix failed: %v\n", err)
+				} else {
+					fmt.Println("    ✓ fixed")
+					continue
+				}
+			} else if issue.fix != nil {
+				fixable++
+			}
+			if issue.hint != "" {
+				fmt.Printf("    → %s\n", issue.hint)
+			}
+			remaining++
+		}
+	}
+
+	if remaining > 0 {
+		if fixable > 0 {
+			fmt.Printf("\n%d of them can be repaired with 'mcc doctor --fix'.\n", fixable)
+		}
+		return fmt.Errorf("%d problem(s) found", remaining)
+	}
+	return nil
+}
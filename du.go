@@ -0,0 +1,334 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// diskCategory groups the entries claude creates in a profile directory.
+// They only hold data claude can do without, so mcc prune may remove old
+// files from them.
+type diskCategory struct {
+	name    string
+	entries []string
+	// keep, if set, picks out what below an entry is not the category's
+	// own, given its path relative to the entry
+	keep func(rel string, dir bool) bool
+}
+
+var diskCategories = []diskCategory{
+	{name: "transcripts", entries: []string{projectsDirName}, keep: keepProjectEntry},
+	{name: "history", entries: []string{"todos", "file-history", "session-env"}},
+	{name: "snapshots", entries: []string{"shell-snapshots"}},
+	{name: "caches", entries: []string{"statsig", "cache", "debug", "telemetry", "image-cache", "paste-cache"}},
+}
+
+// Everything not in a category above, such as settings, credentials,
+// commands, plans and project memory. mcc prune never touches it.
+const otherCategory = "other"
+
+var sessionDirPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// keepProjectEntry leaves everything in projects/<dir> alone except the
+// session transcripts (<session>.jsonl) and the session directories next
+// to them, so that project memory and whatever else claude keeps there
+// survive mcc prune.
+func keepProjectEntry(rel string, dir bool) bool {
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	switch len(parts) {
+	case 1:
+		return !dir
+	case 2:
+		if dir {
+			return !sessionDirPattern.MatchString(parts[1])
+		}
+		return filepath.Ext(parts[1]) != ".jsonl"
+	}
+	return false
+}
+
+func categoryOf(entry string) *diskCategory {
+	for i := range diskCategories {
+		if containsString(diskCategories[i].entries, entry) {
+			return &diskCategories[i]
+		}
+	}
+	return nil
+}
+
+// treeSize adds up the sizes of the files below path without following
+// symlinks.
+func treeSize(path string) (size int64, err error) {
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == path {
+				return nil
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size, err
+}
+
+// profileDiskUsage returns a profile's disk use per category. Entries
+// shared with mcc link live in ~/.mcc/shared and aren't counted here.
+func profileDiskUsage(profile string) (map[string]int64, error) {
+	profileDir := filepath.Join(getProfilesDir(), profile)
+	entries, err := os.ReadDir(profileDir)
+	if err != nil {
+		return nil, err
+	}
+	usage := make(map[string]int64)
+	for _, entry := range entries {
+		if entry.Type()&os.ModeSymlink != 0 {
+			continue
+		}
+		path := filepath.Join(profileDir, entry.Name())
+		category := categoryOf(entry.Name())
+		if category == nil {
+			size, err := treeSize(path)
+			if err != nil {
+				return nil, err
+			}
+			usage[otherCategory] += size
+			continue
+		}
+		kept, err := walkCategory(path, category, func(path string, info fs.FileInfo) error {
+			usage[category.name] += info.Size()
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		usage[otherCategory] += kept
+	}
+	return usage, nil
+}
+
+// walkCategory calls fn for each regular file below root, an entry of
+// category, that belongs to the category, and returns the size of the
+// files it keeps out.
+func walkCategory(root string, category *diskCategory, fn func(path string, info fs.FileInfo) error) (kept int64, err error) {
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if path != root && category.keep != nil {
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			if category.keep(rel, d.IsDir()) {
+				size, err := treeSize(path)
+				kept += size
+				if err == nil && d.IsDir() {
+					return filepath.SkipDir
+				}
+				return err
+			}
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		return fn(path, info)
+	})
+	return kept, err
+}
+
+// formatBytes abbreviates a byte count, e.g. 1536 → 1.5K.
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1fG", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fM", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fK", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
+}
+
+func showDiskUsage(profile string) error {
+	var names []string
+	if profile != "" {
+		names = []string{profile}
+	}
+	profiles, err := resolveProfiles(names)
+	if err != nil {
+		return err
+	}
+
+	var columns []string
+	for _, category := range diskCategories {
+		columns = append(columns, category.name)
+	}
+	columns = append(columns, otherCategory)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "PROFILE\t%s\tTOTAL\n", strings.ToUpper(strings.Join(columns, "\t")))
+	var total int64
+	for _, name := range profiles {
+		usage, err := profileDiskUsage(name)
+		if err != nil {
+			return fmt.Errorf("failed to measure profile '%s': %w", name, err)
+		}
+		var sum int64
+		row := name
+		for _, column := range columns {
+			row += "\t" + formatBytes(usage[column])
+			sum += usage[column]
+		}
+		fmt.Fprintf(w, "%s\t%s\n", row, formatBytes(sum))
+		total += sum
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if profile == "" {
+		shared, err := treeSize(getSharedDir())
+		if err != nil {
+			return err
+		}
+		fmt.Println()
+		if shared > 0 {
+			fmt.Printf("Shared entries (mcc link): %s\n", formatBytes(shared))
+			total += shared
+		}
+		fmt.Printf("Total: %s\n", formatBytes(total))
+	}
+	return nil
+}
+
+// pruneStats counts what mcc prune removed, or would remove, per category.
+type pruneStats struct {
+	files      int
+	bytes      int64
+	byCategory map[string]int64
+}
+
+// pruneEntry removes the files of a category below one of its entries
+// last modified before cutoff, and the directories this leaves empty. The
+// entry itself is kept.
+func pruneEntry(root string, category *diskCategory, cutoff time.Time, dryRun bool, stats *pruneStats) error {
+	var dirs []string
+	_, err := walkCategory(root, category, func(path string, info fs.FileInfo) error {
+		// Belt and braces: never remove anything that looks like a login
+		// or settings, whatever directory it turns up in
+		name := info.Name()
+		if isSyncExcluded(name) || strings.HasPrefix(name, "settings") {
+			return nil
+		}
+		if !info.ModTime().Before(cutoff) {
+			return nil
+		}
+		if !dryRun {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			dirs = append(dirs, filepath.Dir(path))
+		}
+		stats.files++
+		stats.bytes += info.Size()
+		stats.byCategory[category.name] += info.Size()
+		return nil
+	})
+	if err != nil || dryRun {
+		return err
+	}
+
+	// Remove the directories emptied above, and parents emptied in turn
+	for _, dir := range dirs {
+		for ; dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+			if entries, err := os.ReadDir(dir); err != nil || len(entries) > 0 || os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// pruneProfiles removes transcripts, history, snapshots and caches last
+// modified before cutoff. Credentials, settings and anything else outside
+// those categories are never touched, and neither are shared entries,
+// which other profiles use too.
+func pruneProfiles(profile string, olderThan string, dryRun bool) error {
+	var names []string
+	if profile != "" {
+		names = []string{profile}
+	}
+	profiles, err := resolveProfiles(names)
+	if err != nil {
+		return err
+	}
+	cutoff, err := parseSince(olderThan)
+	if err != nil || cutoff.IsZero() {
+		return usageError("invalid --older-than value '%s' (use e.g. 30d, 2w or 2006-01-02)", olderThan)
+	}
+
+	verb := "Removed"
+	if dryRun {
+		verb = "Would remove"
+		fmt.Println("Dry run: nothing is deleted.")
+	}
+	fmt.Printf("Pruning files last modified before %s\n", cutoff.Local().Format("2006-01-02 15:04"))
+
+	var totalFiles int
+	var totalBytes int64
+	for _, name := range profiles {
+		profileDir := filepath.Join(getProfilesDir(), name)
+		stats := &pruneStats{byCategory: make(map[string]int64)}
+		for i := range diskCategories {
+			category := &diskCategories[i]
+			for _, entry := range category.entries {
+				path := filepath.Join(profileDir, entry)
+				// Lstat, so that shared entries (symlinks) are skipped
+				if info, err := os.Lstat(path); err != nil || !info.IsDir() {
+					continue
+				}
+				if err := pruneEntry(path, category, cutoff, dryRun, stats); err != nil {
+					return fmt.Errorf("failed to prune %s of profile '%s': %w", entry, name, err)
+				}
+			}
+		}
+		if stats.files == 0 {
+			continue
+		}
+
+		var parts []string
+		for _, category := range diskCategories {
+			if size := stats.byCategory[category.name]; size > 0 {
+				parts = append(parts, fmt.Sprintf("%s %s", category.name, formatBytes(size)))
+			}
+		}
+		fmt.Printf("  %s: %d file(s), %s (%s)\n", name, stats.files, formatBytes(stats.bytes), strings.Join(parts, ", "))
+		totalFiles += stats.files
+		totalBytes += stats.bytes
+	}
+
+	if totalFiles == 0 {
+		fmt.Println("Nothing to prune")
+		return nil
+	}
+	fmt.Printf("✓ %s %d file(s), %s\n", verb, totalFiles, formatBytes(totalBytes))
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeepProjectEntry(t *testing.T) {
+	const session = "0b6a4f3e-2c1d-4e5f-8a9b-0c1d2e3f4a5b"
+	tests := []struct {
+		rel  string
+		dir  bool
+		keep bool
+	}{
+		{"-home-me-app", true, false},
+		{"stray.txt", false, true},
+		{"-home-me-app/" + session + ".jsonl", false, false},
+		{"-home-me-app/" + session, true, false},
+		{"-home-me-app/" + session + "/subagents/agent.jsonl", false, false},
+		{"-home-me-app/memory", true, true},
+		{"-home-me-app/notes.md", false, true},
+		{"-home-me-app/drafts", true, true},
+	}
+	for _, tt := range tests {
+		if got := keepProjectEntry(filepath.FromSlash(tt.rel), tt.dir); got != tt.keep {
+			t.Errorf("keepProjectEntry(%q, %v) = %v, want %v", tt.rel, tt.dir, got, tt.keep)
+		}
+	}
+}
+
+func TestPruneProfiles(t *testing.T) {
+	useTempHome(t)
+	profile := filepath.Join(getProfilesDir(), "work")
+	const session = "0b6a4f3e-2c1d-4e5f-8a9b-0c1d2e3f4a5b"
+	removed := []string{
+		"projects/-app/" + session + ".jsonl",
+		"projects/-app/" + session + "/tool-results/out.txt",
+		"todos/old.json",
+		"shell-snapshots/snapshot.sh",
+	}
+	kept := []string{
+		"projects/-app/memory/MEMORY.md",
+		"projects/-app/notes.md",
+		"plans/plan.md",
+		"settings.json",
+		"todos/settings.json",
+		"CLAUDE.md",
+	}
+	old := time.Now().Add(-60 * 24 * time.Hour)
+	for _, rel := range append(append([]string{}, removed...), kept...) {
+		path := filepath.Join(profile, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recent := filepath.Join(profile, "projects", "-app", "recent.jsonl")
+	if err := os.WriteFile(recent, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pruneProfiles("work", "30d", false); err != nil {
+		t.Fatal(err)
+	}
+	for _, rel := range removed {
+		if _, err := os.Lstat(filepath.Join(profile, filepath.FromSlash(rel))); !os.IsNotExist(err) {
+			t.Errorf("%s was not pruned", rel)
+		}
+	}
+	if _, err := os.Lstat(filepath.Join(profile, "projects", "-app", session)); !os.IsNotExist(err) {
+		t.Errorf("emptied session directory was not removed")
+	}
+	for _, rel := range append(kept, "projects/-app/recent.jsonl") {
+		if _, err := os.Lstat(filepath.Join(profile, filepath.FromSlash(rel))); err != nil {
+			t.Errorf("%s was pruned: %v", rel, err)
+		}
+	}
+}
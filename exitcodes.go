@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+)
+
+// Exit codes of mcc, listed in the readme. They follow sysexits.h where
+// it has a match. A launched claude's own exit status is passed on as is.
+const (
+	exitFailure         = 1
+	exitUsage           = 64  // invalid arguments
+	exitDataError       = 65  // a state file is corrupt or too new
+	exitProfileNotFound = 66  // the named profile doesn't exist
+	exitIOError         = 74  // reading or writing a file failed
+	exitClaudeNotFound  = 127 // claude isn't on PATH, like a shell's "command not found"
+)
+
+// exitCodeError attaches an exit code to an error.
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string { return e.err.Error() }
+func (e *exitCodeError) Unwrap() error { return e.err }
+
+func withExitCode(code int, err error) error {
+	return &exitCodeError{code: code, err: err}
+}
+
+// usageError is an error about the command line, exiting with exitUsage.
+func usageError(format string, a ...any) error {
+	return withExitCode(exitUsage, fmt.Errorf(format, a...))
+}
+
+// profileNotFound is the error for a profile name that doesn't exist.
+func profileNotFound(name string) error {
+	return withExitCode(exitProfileNotFound, fmt.Errorf("profile '%s' does not exist", name))
+}
+
+// exitCodeOf picks the exit code for an error.
+func exitCodeOf(err error) int {
+	var coded *exitCodeError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+	var status claudeExitStatus
+	if errors.As(err, &status) {
+		return int(status)
+	}
+	if errors.Is(err, exec.ErrNotFound) {
+		return exitClaudeNotFound
+	}
+	var pathErr *fs.PathError
+	var linkErr *os.LinkError
+	if errors.As(err, &pathErr) || errors.As(err, &linkErr) {
+		return exitIOError
+	}
+	return exitFailure
+}
+
+// fail reports err on stderr and exits with its exit code.
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	exit(exitCodeOf(err))
+}
+
+// failUsage reports a malformed command line with the expected usage and
+// exits with exitUsage.
+func failUsage(problem string, usage ...string) {
+	fmt.Fprintf(os.Stderr, "Error: %s\n", problem)
+	for _, line := range usage {
+		fmt.Fprintf(os.Stderr, "Usage: %s\n", line)
+	}
+	exit(exitUsage)
+}
+
+// Set by --quiet or MCC_QUIET, see parseGlobalFlags.
+var quiet bool
+
+// notef prints a hint or progress message on stderr unless mcc is quiet.
+func notef(format string, a ...any) {
+	if !quiet {
+		fmt.Fprintf(os.Stderr, format, a...)
+	}
+}
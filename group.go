@@ -0,0 +1,287 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Environment that decides which files mcc uses and which claude it runs,
+// passed on to the mcc in each tmux pane: panes get the environment of the
+// tmux server, which may have been started from somewhere else. The key_env
+// variables of the launched profiles are passed on too.
+var paneEnv = []string{"HOME", "PATH", "CLAUDE_CONFIG_DIR", "MCC_HOME", "MCC_XDG", "XDG_CONFIG_HOME", "XDG_DATA_HOME", "MCC_QUIET"}
+
+func createGroup(name string, profiles []string, force bool) error {
+	if name == "" || strings.ContainsAny(name, " ,:.") {
+		return usageError("invalid group name '%s'", name)
+	}
+	if _, err := resolveProfiles(profiles); err != nil {
+		return err
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if _, ok := config.Groups[name]; ok && !force {
+		return fmt.Errorf("group '%s' already exists; use --force to replace it", name)
+	}
+	if config.Groups == nil {
+		config.Groups = make(map[string][]string)
+	}
+	config.Groups[name] = profiles
+	if err := saveConfig(config); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	fmt.Printf("✓ Created group %s: %s\n", name, strings.Join(profiles, ", "))
+	fmt.Printf("  Launch it with: mcc run --group %s\n", name)
+	return nil
+}
+
+func deleteGroup(name string) error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if _, ok := config.Groups[name]; !ok {
+		return fmt.Errorf("group '%s' does not exist", name)
+	}
+	delete(config.Groups, name)
+	if err := saveConfig(config); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	fmt.Printf("✓ Deleted group: %s\n", name)
+	return nil
+}
+
+func showGroups() error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if len(config.Groups) == 0 {
+		fmt.Println("No groups. Use 'mcc group create <group> <profile>...' to make one.")
+		return nil
+	}
+	names := make([]string, 0, len(config.Groups))
+	for name := range config.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s: %s\n", name, strings.Join(config.Groups[name], ", "))
+	}
+	return nil
+}
+
+// groupProfiles returns the profiles of a group.
+func groupProfiles(name string) ([]string, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	profiles, ok := config.Groups[name]
+	if !ok {
+		return nil, usageError("group '%s' does not exist; see mcc group list", name)
+	}
+	return resolveProfiles(profiles)
+}
+
+// tmuxOutput runs a tmux command and returns what it printed.
+func tmuxOutput(args ...string) (string, error) {
+	out, err := exec.Command("tmux", args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("tmux %s: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("tmux %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// launchTmux opens a tmux window with one pane per profile, each running
+// claude in the working directory through 'mcc run --no-switch', so that
+// every pane gets its profile's usual launch environment. Inside tmux the
+// window is added to the current session; otherwise a new session named
+// after label is created and attached.
+func launchTmux(profiles []string, label string) error {
+	if runtime.GOOS == "windows" {
+		return fmt.Errorf("launching profiles in tmux isn't supported on Windows; open a terminal per profile with mcc run <name> --no-switch")
+	}
+	if len(profiles) == 0 {
+		return usageError("no profiles to launch")
+	}
+	if _, err := resolveProfiles(profiles); err != nil {
+		return err
+	}
+	// The variables the profiles take their API keys from
+	var keyEnv []string
+	for _, profile := range profiles {
+		meta, err := loadProfileMeta(filepath.Join(getProfilesDir(), profile))
+		if err != nil {
+			return err
+		}
+		if _, ok := os.LookupEnv(meta.KeyEnv); ok && !containsString(keyEnv, meta.KeyEnv) {
+			keyEnv = append(keyEnv, meta.KeyEnv)
+		}
+	}
+	if _, err := exec.LookPath("tmux"); err != nil {
+		return fmt.Errorf("tmux not found in PATH: %w", err)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	// Given as separate arguments, tmux runs the command without a shell
+	paneCommand := func(profile string) []string {
+		command := []string{"env"}
+		for _, name := range paneEnv {
+			if value, ok := os.LookupEnv(name); ok {
+				command = append(command, name+"="+value)
+			}
+		}
+		return append(command, exe, "run", profile, "--no-switch")
+	}
+
+	if label == "" {
+		label = "mcc"
+	}
+	inside := os.Getenv("TMUX") != ""
+	session := label
+	var sessionID, window, firstPane string
+	if inside {
+		if sessionID, err = tmuxOutput("display-message", "-p", "#{session_id}"); err != nil {
+			return err
+		}
+	} else {
+		// tmux session names must be unique
+		for i := 2; exec.Command("tmux", "has-session", "-t", "="+session).Run() == nil; i++ {
+			session = fmt.Sprintf("%s-%d", label, i)
+		}
+		// The first pane waits in cat until the keys are in the session's
+		// environment, and is then respawned with its profile
+		out, err := tmuxOutput("new-session", "-d", "-P", "-F", "#{session_id} #{window_id} #{pane_id}", "-s", session, "-n", label, "-c", cwd, "cat")
+		if err != nil {
+			return err
+		}
+		ids := strings.Fields(out)
+		if len(ids) != 3 {
+			return fmt.Errorf("unexpected output from tmux: %s", out)
+		}
+		sessionID, window, firstPane = ids[0], ids[1], ids[2]
+	}
+
+	// The panes find the keys in the session's environment rather than on
+	// their command lines, which ps and tmux would show. Once they have
+	// started, the keys are taken out again.
+	if err := setTmuxEnv(sessionID, keyEnv); err != nil {
+		return err
+	}
+	clearKeys := func() {
+		for _, name := range keyEnv {
+			tmuxOutput("set-environment", "-t", sessionID, "-u", name)
+		}
+		keyEnv = nil
+	}
+	defer clearKeys()
+
+	if inside {
+		out, err := tmuxOutput(append([]string{"new-window", "-P", "-F", "#{window_id} #{pane_id}", "-n", label, "-c", cwd}, paneCommand(profiles[0])...)...)
+		if err != nil {
+			return err
+		}
+		ids := strings.Fields(out)
+		if len(ids) != 2 {
+			return fmt.Errorf("unexpected output from tmux: %s", out)
+		}
+		window, firstPane = ids[0], ids[1]
+	} else if _, err := tmuxOutput(append([]string{"respawn-pane", "-k", "-t", firstPane, "-c", cwd}, paneCommand(profiles[0])...)...); err != nil {
+		return err
+	}
+	panes := []string{firstPane}
+
+	for _, profile := range profiles[1:] {
+		pane, err := tmuxOutput(append([]string{"split-window", "-P", "-F", "#{pane_id}", "-t", window, "-c", cwd}, paneCommand(profile)...)...)
+		if err != nil {
+			return err
+		}
+		panes = append(panes, pane)
+		// Keep room for the next split
+		if _, err := tmuxOutput("select-layout", "-t", window, "tiled"); err != nil {
+			return err
+		}
+	}
+	// Show each pane's profile in its border
+	for i, pane := range panes {
+		tmuxOutput("select-pane", "-t", pane, "-T", profiles[i])
+	}
+	tmuxOutput("set-window-option", "-t", window, "pane-border-status", "top")
+	tmuxOutput("select-pane", "-t", panes[0])
+	clearKeys()
+
+	fmt.Printf("✓ Launched %s in tmux\n", strings.Join(profiles, ", "))
+	if inside {
+		return nil
+	}
+	attach := exec.Command("tmux", "attach-session", "-t", sessionID)
+	attach.Stdin = os.Stdin
+	attach.Stdout = os.Stdout
+	attach.Stderr = os.Stderr
+	return attach.Run()
+}
+
+// tmuxQuote quotes s as a string in a tmux command file.
+func tmuxQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\', '$':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// setTmuxEnv sets the named variables, with their values in mcc's
+// environment, in a tmux session's environment. tmux reads the commands
+// from a file only mcc can read, so the values stay off command lines.
+func setTmuxEnv(session string, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	f, err := os.CreateTemp("", "mcc-tmux-*.conf")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	for _, name := range names {
+		fmt.Fprintf(f, "set-environment -t %s %s %s\n", tmuxQuote(session), tmuxQuote(name), tmuxQuote(os.Getenv(name)))
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	_, err = tmuxOutput("source-file", f.Name())
+	return err
+}
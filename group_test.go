@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestTmuxQuote(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", `""`},
+		{"sk-abc", `"sk-abc"`},
+		{`a"b\c`, `"a\"b\\c"`},
+		{"$HOME #{pane_id}", `"\$HOME #{pane_id}"`},
+		{"line\nnext", `"line\nnext"`},
+		{"é'x", `"é'x"`},
+	}
+	for _, tt := range tests {
+		if got := tmuxQuote(tt.in); got != tt.want {
+			t.Errorf("tmuxQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSetTmuxEnv(t *testing.T) {
+	if _, err := exec.LookPath("tmux"); err != nil {
+		t.Skip("tmux not found")
+	}
+	t.Setenv("TMUX_TMPDIR", t.TempDir())
+	t.Setenv("TMUX", "")
+	if err := exec.Command("tmux", "new-session", "-d", "-s", "test", "cat").Run(); err != nil {
+		t.Skipf("cannot start tmux: %v", err)
+	}
+	defer exec.Command("tmux", "kill-server").Run()
+
+	const value = `sk-a"b$c\d é'x #{pane_id}`
+	t.Setenv("MCC_TEST_KEY", value)
+	if err := setTmuxEnv("test", []string{"MCC_TEST_KEY"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := tmuxOutput("show-environment", "-t", "test", "MCC_TEST_KEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "MCC_TEST_KEY=" + value; got != want {
+		t.Errorf("session environment has %s, want %s", got, want)
+	}
+}
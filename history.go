@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const historyFileName = "history.jsonl"
+
+// Launch log events.
+const (
+	historyLaunch = "launch"
+	historyExit   = "exit"
+)
+
+// historyRecord is one line of the launch log. Launches and exits are
+// separate lines matched up by pid, since mcc usually replaces itself with
+// claude and never sees it exit.
+type historyRecord struct {
+	Event      string    `json:"event"`
+	Time       time.Time `json:"time"`
+	Profile    string    `json:"profile"`
+	Provider   string    `json:"provider,omitempty"`
+	Cwd        string    `json:"cwd,omitempty"`
+	PID        int       `json:"pid"`
+	ExitStatus *int      `json:"exit_status,omitempty"`
+}
+
+// launchEntry is a launch with its exit, as shown by mcc history.
+type launchEntry struct {
+	historyRecord
+	Ended *time.Time `json:"ended,omitempty"`
+}
+
+func getHistoryPath() string {
+	return filepath.Join(getMccDir(), historyFileName)
+}
+
+func appendHistory(record historyRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(getHistoryPath(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// recordLaunch logs that claude was started in a profile. A launch must
+// not fail because of the log, so errors are only reported.
+func recordLaunch(profilePath string, pid int) {
+	cwd, _ := os.Getwd()
+	provider := ""
+	if meta, err := loadProfileMeta(profilePath); err == nil {
+		provider = meta.Provider
+	}
+	err := appendHistory(historyRecord{
+		Event:    historyLaunch,
+		Time:     time.Now(),
+		Profile:  filepath.Base(profilePath),
+		Provider: provider,
+		Cwd:      cwd,
+		PID:      pid,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not write launch history: %v\n", err)
+	}
+}
+
+// recordExit logs claude's exit status for launches mcc waited for.
+func recordExit(profilePath string, pid int, code int) {
+	err := appendHistory(historyRecord{
+		Event:      historyExit,
+		Time:       time.Now(),
+		Profile:    filepath.Base(profilePath),
+		PID:        pid,
+		ExitStatus: &code,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not write launch history: %v\n", err)
+	}
+}
+
+// loadHistory returns the launches in the log, oldest first, with the
+// exits matched to them.
+func loadHistory() ([]*launchEntry, error) {
+	f, err := os.Open(getHistoryPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var launches []*launchEntry
+	running := make(map[int]*launchEntry)
+	err = forEachLine(f, func(line []byte) error {
+		var record historyRecord
+		if len(bytes.TrimSpace(line)) == 0 || json.Unmarshal(line, &record) != nil {
+			return nil
+		}
+		switch record.Event {
+		case historyLaunch:
+			launch := &launchEntry{historyRecord: record}
+			launches = append(launches, launch)
+			running[record.PID] = launch
+		case historyExit:
+			if launch, ok := running[record.PID]; ok && launch.Profile == record.Profile {
+				launch.ExitStatus = record.ExitStatus
+				ended := record.Time
+				launch.Ended = &ended
+				delete(running, record.PID)
+			}
+		}
+		return nil
+	})
+	return launches, err
+}
+
+// lastUsed returns when claude was last launched in each profile.
+func lastUsed() (map[string]time.Time, error) {
+	launches, err := loadHistory()
+	if err != nil {
+		return nil, err
+	}
+	last := make(map[string]time.Time)
+	for _, launch := range launches {
+		if launch.Time.After(last[launch.Profile]) {
+			last[launch.Profile] = launch.Time
+		}
+	}
+	return last, nil
+}
+
+func showHistory(profile, since string, limit int, asJSON bool) error {
+	if profile != "" && !profileExists(profile) {
+		return profileNotFound(profile)
+	}
+	start, err := parseSince(since)
+	if err != nil {
+		return err
+	}
+	launches, err := loadHistory()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", getHistoryPath(), err)
+	}
+
+	// Newest first
+	var shown []*launchEntry
+	for i := len(launches) - 1; i >= 0; i-- {
+		launch := launches[i]
+		if (profile != "" && launch.Profile != profile) || launch.Time.Before(start) {
+			continue
+		}
+		if limit > 0 && len(shown) == limit {
+			break
+		}
+		shown = append(shown, launch)
+	}
+
+	if asJSON {
+		if shown == nil {
+			shown = []*launchEntry{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(shown)
+	}
+	if len(shown) == 0 {
+		fmt.Println("No launches recorded yet")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tPROFILE\tPROVIDER\tPID\tEXIT\tDIRECTORY")
+	for _, launch := range shown {
+		exit := "-"
+		if launch.ExitStatus != nil {
+			exit = strconv.Itoa(*launch.ExitStatus)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n",
+			launch.Time.Local().Format("2006-01-02 15:04"), launch.Profile, launch.Provider,
+			launch.PID, exit, shortenHome(launch.Cwd))
+	}
+	return w.Flush()
+}
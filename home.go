@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// mccLayout is where mcc keeps its files: config.json in config, everything
+// else (profiles, the current link, shared entries, logs) in data. Both are
+// ~/.mcc unless MCC_HOME, --home or the XDG layout say otherwise.
+type mccLayout struct {
+	data   string
+	config string
+	source string // what picked the layout, shown by mcc home
+}
+
+// Resolved on first use, see getLayout.
+var layout *mccLayout
+
+func userHome() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
+		os.Exit(exitIOError)
+	}
+	return home
+}
+
+// setMccHome makes mcc use dir for all its files, as --home does. It is
+// exported as MCC_HOME so that mcc commands started from here agree.
+func setMccHome(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("invalid mcc home '%s': %w", dir, err)
+	}
+	layout = nil
+	return os.Setenv("MCC_HOME", abs)
+}
+
+func xdgDir(env, fallback string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "mcc")
+	}
+	return filepath.Join(userHome(), fallback, "mcc")
+}
+
+func xdgLayout() *mccLayout {
+	return &mccLayout{
+		data:   xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share")),
+		config: xdgDir("XDG_CONFIG_HOME", ".config"),
+		source: "XDG",
+	}
+}
+
+func getLayout() *mccLayout {
+	if layout != nil {
+		return layout
+	}
+	if dir := os.Getenv("MCC_HOME"); dir != "" {
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		layout = &mccLayout{data: dir, config: dir, source: "MCC_HOME"}
+		return layout
+	}
+
+	legacy := filepath.Join(userHome(), mccDirName)
+	xdg := xdgLayout()
+	switch {
+	case envEnabled("MCC_XDG"):
+		layout = xdg
+	case !isDir(legacy) && isDir(xdg.data):
+		// Left there by 'mcc home move --xdg'
+		layout = xdg
+	default:
+		layout = &mccLayout{data: legacy, config: legacy, source: "default"}
+	}
+	return layout
+}
+
+func getMccDir() string {
+	return getLayout().data
+}
+
+func getMccConfigDir() string {
+	return getLayout().config
+}
+
+// customHome returns the directory set with MCC_HOME or --home, which new
+// shells need to be told about, or "".
+func customHome() string {
+	if l := getLayout(); l.source == "MCC_HOME" {
+		return l.data
+	}
+	return ""
+}
+
+func showHome() {
+	l := getLayout()
+	fmt.Printf("Data:   %s\n", l.data)
+	fmt.Printf("Config: %s\n", l.config)
+	switch l.source {
+	case "MCC_HOME":
+		fmt.Println("(set by MCC_HOME or --home)")
+	case "XDG":
+		fmt.Println("(XDG layout)")
+	}
+}
+
+// moveEntry moves a file or directory, copying it when a rename can't,
+// e.g. across filesystems.
+func moveEntry(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	if err := copyDir(src, dst); err != nil {
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// relinkShared points the shared-entry links of every profile, which are
+// absolute, from oldShared to the current shared directory.
+func relinkShared(oldShared string) error {
+	profiles, err := listProfiles()
+	if err != nil {
+		return err
+	}
+	for _, profile := range profiles {
+		profilePath := filepath.Join(getProfilesDir(), profile)
+		entries, err := os.ReadDir(profilePath)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			path := filepath.Join(profilePath, entry.Name())
+			target, err := os.Readlink(path)
+			if err != nil || filepath.Dir(target) != oldShared {
+				continue
+			}
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			if err := os.Symlink(filepath.Join(getSharedDir(), entry.Name()), path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// moveHome moves mcc's files from where they are now to dir, or to the XDG
+// directories when toXDG is set, and repoints the links and shell setup.
+func moveHome(dir string, toXDG, force bool) error {
+	from := getLayout()
+	var to *mccLayout
+	switch {
+	case toXDG && dir != "":
+		return usageError("give either a directory or --xdg")
+	case toXDG:
+		if from.source == "MCC_HOME" {
+			return fmt.Errorf("MCC_HOME is set, unset it first so that mcc finds the XDG directories afterwards")
+		}
+		to = xdgLayout()
+	case dir != "":
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		to = &mccLayout{data: abs, config: abs, source: "MCC_HOME"}
+	default:
+		return usageError("give a directory or --xdg")
+	}
+	if to.data == from.data && to.config == from.config {
+		return fmt.Errorf("mcc already uses %s", to.data)
+	}
+	if strings.HasPrefix(to.data+string(filepath.Separator), from.data+string(filepath.Separator)) {
+		return fmt.Errorf("cannot move %s into itself", from.data)
+	}
+	if entries, _ := os.ReadDir(to.data); len(entries) > 0 {
+		return fmt.Errorf("%s is not empty", to.data)
+	}
+	if fileExists(filepath.Join(to.config, configFileName)) {
+		return fmt.Errorf("%s already exists", filepath.Join(to.config, configFileName))
+	}
+	if !force {
+		running, err := claudeProcesses()
+		if err != nil {
+			return fmt.Errorf("cannot tell whether claude is running: %v", err)
+		}
+		if len(running) > 0 {
+			return fmt.Errorf("claude is running and would lose its profile; quit it first, or pass --force")
+		}
+	}
+
+	oldShared := getSharedDir()
+	entries, err := os.ReadDir(from.data)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		// The lock is held while moving; a new one is taken in the new place
+		if entry.Name() == lockFileName {
+			continue
+		}
+		src := filepath.Join(from.data, entry.Name())
+		dst := filepath.Join(to.data, entry.Name())
+		if entry.Name() == configFileName {
+			dst = filepath.Join(to.config, configFileName)
+		}
+		if err := moveEntry(src, dst); err != nil {
+			return fmt.Errorf("failed to move %s to %s: %w", src, dst, err)
+		}
+	}
+	if from.config != from.data {
+		if err := moveEntry(filepath.Join(from.config, configFileName), filepath.Join(to.config, configFileName)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		os.Remove(from.config)
+	}
+
+	if to.source == "MCC_HOME" {
+		if err := setMccHome(to.data); err != nil {
+			return err
+		}
+	} else {
+		layout = to
+	}
+	if err := relockState(); err != nil {
+		return err
+	}
+	if err := os.Remove(from.data); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not remove %s: %v\n", from.data, err)
+	}
+	if err := relinkShared(oldShared); err != nil {
+		return fmt.Errorf("failed to repoint shared links: %w", err)
+	}
+	if err := relinkCurrent(); err != nil {
+		return fmt.Errorf("failed to repoint the current link: %w", err)
+	}
+	fmt.Printf("✓ Moved mcc's files to %s\n", shortenHome(to.data))
+	if to.config != to.data {
+		fmt.Printf("  config.json is in %s\n", shortenHome(to.config))
+	}
+
+	updated := false
+	for _, shell := range supportedShells {
+		path, err := shellRCFile(shell)
+		if err != nil {
+			continue
+		}
+		if data, err := os.ReadFile(path); err == nil && strings.Contains(string(data), shellBlockStart) {
+			if err := initShell(shell); err != nil {
+				return err
+			}
+			updated = true
+		}
+	}
+	if !updated {
+		fmt.Printf("  Set CLAUDE_CONFIG_DIR=%s in your shell, or run: mcc init\n", getCurrentLink())
+		if to.source == "MCC_HOME" {
+			fmt.Printf("  and MCC_HOME=%s so that mcc finds its files\n", to.data)
+		}
+	}
+	return nil
+}
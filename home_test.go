@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMoveHomeKeepsLock(t *testing.T) {
+	home := useTempHome(t)
+	if err := ensureMccStructure(); err != nil {
+		t.Fatal(err)
+	}
+	from := getMccDir()
+	unlock, err := lockState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	to := filepath.Join(home, "vault", "mcc")
+	if err := moveHome(to, false, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(from); !os.IsNotExist(err) {
+		t.Errorf("%s is still there: %v", from, err)
+	}
+	if got, want := stateLock.file.Name(), filepath.Join(to, lockFileName); got != want {
+		t.Errorf("lock held on %s, want %s", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(to, profilesDirName)); err != nil {
+		t.Errorf("profiles not moved: %v", err)
+	}
+}
@@ -9,7 +9,7 @@ import (
 	"syscall"
 )
 
-func launchClaude(profilePath string, extraEnv []string) error {
+func launchClaude(profilePath string, extraEnv []string, args []string) error {
 	// Find claude executable
 	claudePath, err := exec.LookPath("claude")
 	if err != nil {
@@ -22,6 +22,8 @@ func launchClaude(profilePath string, extraEnv []string) error {
 	env = append(env, fmt.Sprintf("CLAUDE_CONFIG_DIR=%s", profilePath))
 	env = append(env, extraEnv...)
 
-	// Use syscall.Exec to replace current process with claude
-	return syscall.Exec(claudePath, []string{"claude"}, env)
+	// Use syscall.Exec to replace current process with claude. It keeps
+	// mcc's pid, which is what the launch log records.
+	recordLaunch(profilePath, os.Getpid())
+	return syscall.Exec(claudePath, append([]string{"claude"}, args...), env)
 }
@@ -8,19 +8,27 @@ import (
 	"os/exec"
 )
 
-func launchClaude(profilePath string, extraEnv []string) error {
+func launchClaude(profilePath string, extraEnv []string, args []string) error {
 	// Find claude executable
 	claudePath, err := exec.LookPath("claude")
 	if err != nil {
 		return fmt.Errorf("claude not found in PATH: %w", err)
 	}
 
-	cmd := exec.Command(claudePath)
+	cmd := exec.Command(claudePath, args...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	cmd.Env = append(os.Environ(), fmt.Sprintf("CLAUDE_CONFIG_DIR=%s", profilePath))
 	cmd.Env = append(cmd.Env, extraEnv...)
 
-	return cmd.Run()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	recordLaunch(profilePath, cmd.Process.Pid)
+	err = cmd.Wait()
+	if cmd.ProcessState != nil {
+		recordExit(profilePath, cmd.Process.Pid, cmd.ProcessState.ExitCode())
+	}
+	return err
 }
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	sharedDirName    = "shared"
+	linkBackupSuffix = ".pre-link"
+)
+
+// Entries that hold per-account state and must never be shared.
+var unshareableEntries = []string{
+	".credentials.json",
+	".claude.json",
+	profileMetaFile,
+}
+
+func getSharedDir() string {
+	return filepath.Join(getMccDir(), sharedDirName)
+}
+
+func validateSharedEntry(entry string) error {
+	if entry == "" || entry == "." || entry == ".." || strings.ContainsAny(entry, "/\\") {
+		return usageError("invalid entry '%s': must be a single file or directory name inside a profile", entry)
+	}
+	for _, name := range unshareableEntries {
+		if strings.EqualFold(entry, name) {
+			return fmt.Errorf("'%s' holds per-account state and cannot be shared", entry)
+		}
+	}
+	return nil
+}
+
+// resolveProfiles returns the given profile names after checking they exist,
+// or every profile when none are given.
+func resolveProfiles(names []string) ([]string, error) {
+	if len(names) == 0 {
+		return listProfiles()
+	}
+	for _, name := range names {
+		if !validProfileName(name) {
+			return nil, usageError("invalid profile name '%s'", name)
+		}
+		if !profileExists(name) {
+			return nil, profileNotFound(name)
+		}
+	}
+	return names, nil
+}
+
+// isSharedLink reports whether the entry inside profilePath is a symlink
+// into the shared directory.
+func isSharedLink(profilePath, entry string) bool {
+	target, err := os.Readlink(filepath.Join(profilePath, entry))
+	if err != nil {
+		return false
+	}
+	return target == filepath.Join(getSharedDir(), entry)
+}
+
+// copyEntry copies a single file or directory from src to dst.
+func copyEntry(src, dst string) error {
+	return copyDir(src, dst)
+}
+
+// seedSharedEntry creates ~/.mcc/shared/<entry> from the first profile that
+// has a real copy of it, preferring the default profile. It returns the name
+// of the profile used as the seed, or "" when the entry was created empty.
+func seedSharedEntry(entry string, profiles []string) (string, error) {
+	if err := os.MkdirAll(getSharedDir(), 0755); err != nil {
+		return "", fmt.Errorf("failed to create shared directory: %w", err)
+	}
+	sharedPath := filepath.Join(getSharedDir(), entry)
+
+	candidates := append([]string{defaultProfile}, profiles...)
+	for _, profile := range candidates {
+		src := filepath.Join(getProfilesDir(), profile, entry)
+		info, err := os.Lstat(src)
+		if err != nil || info.Mode()&os.ModeSymlink != 0 {
+			continue
+		}
+		if err := copyEntry(src, sharedPath); err != nil {
+			return "", fmt.Errorf("failed to copy %s from profile '%s': %w", entry, profile, err)
+		}
+		return profile, nil
+	}
+
+	// Nothing to seed from: names with an extension are files, others directories
+	if filepath.Ext(entry) != "" {
+		return "", writeFileAtomic(sharedPath, nil, 0644)
+	}
+	return "", os.MkdirAll(sharedPath, 0755)
+}
+
+func linkEntry(entry string, names []string) error {
+	if err := validateSharedEntry(entry); err != nil {
+		return err
+	}
+	profiles, err := resolveProfiles(names)
+	if err != nil {
+		return err
+	}
+
+	sharedPath := filepath.Join(getSharedDir(), entry)
+	seed := ""
+	if _, err := os.Stat(sharedPath); os.IsNotExist(err) {
+		seed, err = seedSharedEntry(entry, profiles)
+		if err != nil {
+			return err
+		}
+		if seed != "" {
+			fmt.Printf("✓ Created shared %s from profile: %s\n", entry, seed)
+		} else {
+			fmt.Printf("✓ Created empty shared %s\n", entry)
+		}
+	}
+
+	failed := 0
+	for _, profile := range profiles {
+		profilePath := filepath.Join(getProfilesDir(), profile)
+		entryPath := filepath.Join(profilePath, entry)
+
+		info, err := os.Lstat(entryPath)
+		switch {
+		case os.IsNotExist(err):
+			// Nothing in the way
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "⚠️  %s: %v\n", profile, err)
+			failed++
+			continue
+		case isSharedLink(profilePath, entry):
+			fmt.Printf("  %s: already linked\n", profile)
+			continue
+		case info.Mode()&os.ModeSymlink != 0:
+			fmt.Fprintf(os.Stderr, "⚠️  %s: %s is a symlink to somewhere else, leaving it alone\n", profile, entry)
+			failed++
+			continue
+		case profile == seed:
+			// Its content now lives in the shared directory
+			if err := os.RemoveAll(entryPath); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  %s: %v\n", profile, err)
+				failed++
+				continue
+			}
+		default:
+			backupPath := entryPath + linkBackupSuffix
+			if _, err := os.Lstat(backupPath); err == nil {
+				fmt.Fprintf(os.Stderr, "⚠️  %s: %s already exists, remove it first\n", profile, entry+linkBackupSuffix)
+				failed++
+				continue
+			}
+			if err := os.Rename(entryPath, backupPath); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  %s: %v\n", profile, err)
+				failed++
+				continue
+			}
+			fmt.Printf("  %s: moved existing %s to %s\n", profile, entry, entry+linkBackupSuffix)
+		}
+
+		if err := os.Symlink(sharedPath, entryPath); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  %s: failed to create link: %v\n", profile, err)
+			failed++
+			continue
+		}
+		fmt.Printf("✓ Linked %s in profile: %s\n", entry, profile)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d profile(s) could not be linked", failed)
+	}
+	return nil
+}
+
+func unlinkEntry(entry string, names []string) error {
+	if err := validateSharedEntry(entry); err != nil {
+		return err
+	}
+	profiles, err := resolveProfiles(names)
+	if err != nil {
+		return err
+	}
+
+	sharedPath := filepath.Join(getSharedDir(), entry)
+	failed := 0
+	for _, profile := range profiles {
+		profilePath := filepath.Join(getProfilesDir(), profile)
+		if !isSharedLink(profilePath, entry) {
+			if len(names) > 0 {
+				fmt.Printf("  %s: %s is not linked\n", profile, entry)
+			}
+			continue
+		}
+
+		entryPath := filepath.Join(profilePath, entry)
+		if err := os.Remove(entryPath); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  %s: %v\n", profile, err)
+			failed++
+			continue
+		}
+		// Leave the profile with its own copy of the shared content
+		if _, err := os.Stat(sharedPath); err == nil {
+			if err := copyEntry(sharedPath, entryPath); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  %s: failed to copy shared %s: %v\n", profile, entry, err)
+				failed++
+				continue
+			}
+		}
+		fmt.Printf("✓ Unlinked %s in profile: %s\n", entry, profile)
+		if _, err := os.Lstat(entryPath + linkBackupSuffix); err == nil {
+			fmt.Printf("  (previous copy kept at %s)\n", entry+linkBackupSuffix)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d profile(s) could not be unlinked", failed)
+	}
+	return nil
+}
+
+func showLinks() error {
+	entries, err := os.ReadDir(getSharedDir())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("No shared entries. Use 'mcc link <entry>' to share one.")
+		return nil
+	}
+
+	profiles, err := listProfiles()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Shared entries (%s):\n", getSharedDir())
+	for _, entry := range entries {
+		var linked []string
+		for _, profile := range profiles {
+			if isSharedLink(filepath.Join(getProfilesDir(), profile), entry.Name()) {
+				linked = append(linked, profile)
+			}
+		}
+		sort.Strings(linked)
+		if len(linked) == 0 {
+			fmt.Printf("  %s (not linked)\n", entry.Name())
+		} else {
+			fmt.Printf("  %s → %s\n", entry.Name(), strings.Join(linked, ", "))
+		}
+	}
+	return nil
+}
+
+// brokenSharedLink describes a profile entry whose shared target is missing.
+type brokenSharedLink struct {
+	profile string
+	entry   string
+}
+
+func findBrokenSharedLinks() ([]brokenSharedLink, error) {
+	profiles, err := listProfiles()
+	if err != nil {
+		return nil, err
+	}
+
+	sharedDir := getSharedDir()
+	var broken []brokenSharedLink
+	for _, profile := range profiles {
+		profilePath := filepath.Join(getProfilesDir(), profile)
+		entries, err := os.ReadDir(profilePath)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.Type()&os.ModeSymlink == 0 {
+				continue
+			}
+			target, err := os.Readlink(filepath.Join(profilePath, entry.Name()))
+			if err != nil || filepath.Dir(target) != sharedDir {
+				continue
+			}
+			if _, err := os.Stat(target); err != nil {
+				broken = append(broken, brokenSharedLink{profile: profile, entry: entry.Name()})
+			}
+		}
+	}
+	return broken, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const lockFileName = ".lock"
+
+// The state lock keeps mcc processes in several terminals from changing
+// mcc's files at the same time. It is reentrant within a process, and the
+// system releases it when the process exits.
+var stateLock struct {
+	depth int
+	file  *os.File
+}
+
+// lockState takes the state lock, waiting while another mcc holds it, and
+// returns the function that releases it.
+func lockState() (func(), error) {
+	if stateLock.depth == 0 {
+		dir := getMccDir()
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+		path := filepath.Join(dir, lockFileName)
+		f, err := lockFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		stateLock.file = f
+	}
+	stateLock.depth++
+
+	released := false
+	return func() {
+		if released {
+			return
+		}
+		released = true
+		stateLock.depth--
+		if stateLock.depth == 0 {
+			unlockFile(stateLock.file)
+			stateLock.file = nil
+		}
+	}, nil
+}
+
+// relockState moves the held state lock to the lock file of the current
+// data directory and removes the old one, for mcc home move. The old lock
+// is released only once the new one is taken.
+func relockState() error {
+	if stateLock.depth == 0 {
+		return nil
+	}
+	dir := getMccDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, lockFileName)
+	f, err := lockFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	old := stateLock.file
+	stateLock.file = f
+	unlockFile(old)
+	os.Remove(old.Name())
+	return nil
+}
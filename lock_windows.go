@@ -0,0 +1,41 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// Returned by CreateFile while another process has the file open.
+const errorSharingViolation syscall.Errno = 32
+
+// lockFile opens path without sharing it, which keeps other processes from
+// opening it until it is closed.
+func lockFile(path string) (*os.File, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	waiting := false
+	for {
+		h, err := syscall.CreateFile(name, syscall.GENERIC_READ|syscall.GENERIC_WRITE, 0, nil,
+			syscall.OPEN_ALWAYS, syscall.FILE_ATTRIBUTE_NORMAL, 0)
+		if err == nil {
+			return os.NewFile(uintptr(h), path), nil
+		}
+		if err != errorSharingViolation {
+			return nil, err
+		}
+		if !waiting {
+			notef("Waiting for another mcc to finish...\n")
+			waiting = true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+func unlockFile(f *os.File) {
+	f.Close()
+}
@@ -2,63 +2,156 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
+	"text/tabwriter"
+	"time"
 )
 
 const (
-	mccDirName       = ".mcc"
-	profilesDirName  = "profiles"
-	currentLinkName  = "current"
-	configFileName   = "config.json"
-	defaultProfile   = "default"
-	profileMetaFile  = ".mcc-profile.json"
+	mccDirName      = ".mcc"
+	profilesDirName = "profiles"
+	currentLinkName = "current"
+	configFileName  = "config.json"
+	defaultProfile  = "default"
+	profileMetaFile = ".mcc-profile.json"
 )
 
 type Config struct {
+	SchemaVersion  int    `json:"schema_version"`
 	CurrentProfile string `json:"current_profile"`
+	// Per-provider price overrides, keyed by provider then model pattern
+	Pricing map[string]map[string]modelPrice `json:"pricing,omitempty"`
+	// Order in which 'mcc next' tries profiles
+	Priority []string `json:"priority,omitempty"`
+	// Launch claude supervised (see superviseClaude) by default
+	Supervise bool `json:"supervise,omitempty"`
+	// Profiles plain 'mcc' launches in some directories, see profileForDir
+	Directories []dirRule `json:"directories,omitempty"`
+	// Profiles launched together by 'mcc run --group', keyed by group name
+	Groups map[string][]string `json:"groups,omitempty"`
+}
+
+// dirRule makes plain 'mcc' launch a profile in a directory and below it.
+type dirRule struct {
+	Path    string `json:"path"`
+	Profile string `json:"profile"`
 }
 
 type ProfileMeta struct {
-	Provider string `json:"provider"`
-	APIKey   string `json:"api_key"`
+	SchemaVersion int    `json:"schema_version"`
+	Provider      string `json:"provider"`
+	APIKey        string `json:"api_key"`
+	// Overrides for the provider's endpoint and claude's model
+	BaseURL string `json:"base_url,omitempty"`
+	Model   string `json:"model,omitempty"`
+	// Where to get the API key at launch instead of storing it
+	KeyEnv     string `json:"key_env,omitempty"`
+	KeyCommand string `json:"key_command,omitempty"`
+	// Shown in listings; the color is also passed to claude's environment
+	// as MCC_PROFILE_COLOR
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Color       string   `json:"color,omitempty"`
+	Owner       string   `json:"owner,omitempty"`
 }
 
-func loadProfileMeta(profilePath string) *ProfileMeta {
-	data, err := os.ReadFile(filepath.Join(profilePath, profileMetaFile))
-	if err != nil {
-		return &ProfileMeta{Provider: "claude"}
+// isPlain reports whether meta says no more than the absence of a
+// .mcc-profile.json does: a claude profile with nothing else set.
+func (m *ProfileMeta) isPlain() bool {
+	return m.Provider == "claude" && m.APIKey == "" && m.BaseURL == "" && m.Model == "" &&
+		m.KeyEnv == "" && m.KeyCommand == "" && m.Description == "" && len(m.Tags) == 0 &&
+		m.Color == "" && m.Owner == ""
+}
+
+// hasAPIKey reports whether the profile launches with an API key.
+func (m *ProfileMeta) hasAPIKey() bool {
+	return m.APIKey != "" || m.KeyEnv != "" || m.KeyCommand != ""
+}
+
+// resolveAPIKey returns the profile's API key, running its key command or
+// reading its key variable when it has one.
+func (m *ProfileMeta) resolveAPIKey() (string, error) {
+	switch {
+	case m.KeyCommand != "":
+		shell, flag := "sh", "-c"
+		if runtime.GOOS == "windows" {
+			shell, flag = "cmd", "/C"
+		}
+		cmd := exec.Command(shell, flag, m.KeyCommand)
+		cmd.Stderr = os.Stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("key command '%s' failed: %v", m.KeyCommand, err)
+		}
+		key := strings.TrimSpace(string(out))
+		if key == "" {
+			return "", fmt.Errorf("key command '%s' printed nothing", m.KeyCommand)
+		}
+		return key, nil
+	case m.KeyEnv != "":
+		key := os.Getenv(m.KeyEnv)
+		if key == "" {
+			return "", fmt.Errorf("%s is not set; the profile reads its API key from it", m.KeyEnv)
+		}
+		return key, nil
 	}
+	return m.APIKey, nil
+}
+
+// loadProfileMeta reads a profile's .mcc-profile.json. A profile without
+// one, or without a provider in it, is a claude profile; a corrupt one is
+// an error.
+func loadProfileMeta(profilePath string) (*ProfileMeta, error) {
 	var meta ProfileMeta
-	if err := json.Unmarshal(data, &meta); err != nil {
-		return &ProfileMeta{Provider: "claude"}
+	err := loadVersioned(filepath.Join(profilePath, profileMetaFile), profileMetaMigrations, &meta)
+	if os.IsNotExist(err) {
+		return &ProfileMeta{SchemaVersion: profileMetaSchemaVersion, Provider: "claude"}, nil
+	}
+	if err != nil {
+		return nil, err
 	}
 	if meta.Provider == "" {
 		meta.Provider = "claude"
 	}
-	return &meta
+	return &meta, nil
 }
 
 func saveProfileMeta(profilePath string, meta *ProfileMeta) error {
+	meta.SchemaVersion = profileMetaSchemaVersion
 	data, err := json.MarshalIndent(meta, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(filepath.Join(profilePath, profileMetaFile), data, 0644)
+	// Holds the API key
+	return writeFileAtomic(filepath.Join(profilePath, profileMetaFile), data, 0600)
 }
 
 // ensureOnboardingComplete sets hasCompletedOnboarding in the profile's
 // .claude.json so that the claude CLI skips the login/onboarding flow.
 // This is required for API-key-based providers like Kimi.
 func ensureOnboardingComplete(profilePath string) error {
+	unlock, err := lockState()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	claudeJSON := filepath.Join(profilePath, ".claude.json")
 	data := make(map[string]interface{})
 
 	if raw, err := os.ReadFile(claudeJSON); err == nil {
-		json.Unmarshal(raw, &data)
+		// Rewriting a file we can't parse would throw away the login
+		if err := json.Unmarshal(raw, &data); err != nil {
+			return corruptFile(claudeJSON, raw, err)
+		}
 	}
 
 	if v, ok := data["hasCompletedOnboarding"]; ok {
@@ -72,28 +165,34 @@ func ensureOnboardingComplete(profilePath string) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(claudeJSON, out, 0600)
+	return writeFileAtomic(claudeJSON, out, 0600)
 }
 
-func getProviderEnv(meta *ProfileMeta) []string {
-	switch meta.Provider {
-	case "kimi":
-		return []string{
-			"ANTHROPIC_BASE_URL=https://api.kimi.com/coding/",
-			"ANTHROPIC_API_KEY=" + meta.APIKey,
-		}
-	default: // "claude" or empty
-		return nil
-	}
+// Endpoints of the providers other than claude.
+var providerBaseURLs = map[string]string{
+	"kimi": "https://api.kimi.com/coding/",
 }
 
-func getMccDir() string {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
-		os.Exit(1)
+func getProviderEnv(meta *ProfileMeta) ([]string, error) {
+	var env []string
+	baseURL := meta.BaseURL
+	if baseURL == "" {
+		baseURL = providerBaseURLs[meta.Provider]
+	}
+	if baseURL != "" {
+		env = append(env, "ANTHROPIC_BASE_URL="+baseURL)
 	}
-	return filepath.Join(home, mccDirName)
+	if meta.Provider != "claude" || meta.hasAPIKey() {
+		key, err := meta.resolveAPIKey()
+		if err != nil {
+			return nil, err
+		}
+		env = append(env, "ANTHROPIC_API_KEY="+key)
+	}
+	if meta.Model != "" {
+		env = append(env, "ANTHROPIC_MODEL="+meta.Model)
+	}
+	return env, nil
 }
 
 func getProfilesDir() string {
@@ -105,43 +204,43 @@ func getCurrentLink() string {
 }
 
 func getConfigPath() string {
-	return filepath.Join(getMccDir(), configFileName)
+	return filepath.Join(getMccConfigDir(), configFileName)
 }
 
 func getClaudeDir() string {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
-		os.Exit(1)
-	}
-	return filepath.Join(home, ".claude")
+	return filepath.Join(userHome(), ".claude")
 }
 
 func loadConfig() (*Config, error) {
-	configPath := getConfigPath()
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return &Config{CurrentProfile: defaultProfile}, nil
-		}
-		return nil, err
-	}
 	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
+	err := loadVersioned(getConfigPath(), configMigrations, &config)
+	if os.IsNotExist(err) {
+		return &Config{SchemaVersion: configSchemaVersion, CurrentProfile: defaultProfile}, nil
+	}
+	if err != nil {
 		return nil, err
 	}
 	return &config, nil
 }
 
 func saveConfig(config *Config) error {
+	config.SchemaVersion = configSchemaVersion
 	data, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(getConfigPath(), data, 0644)
+	return writeFileAtomic(getConfigPath(), data, 0644)
+}
+
+// validProfileName reports whether name can be a profile's directory name.
+func validProfileName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\\:*?\"<>|")
 }
 
 func profileExists(name string) bool {
+	if !validProfileName(name) {
+		return false
+	}
 	profilePath := filepath.Join(getProfilesDir(), name)
 	info, err := os.Stat(profilePath)
 	return err == nil && info.IsDir()
@@ -168,12 +267,21 @@ func listProfiles() ([]string, error) {
 }
 
 func ensureMccStructure() error {
+	unlock, err := lockState()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	profilesDir := getProfilesDir()
 
 	// Create mcc directory
 	if err := os.MkdirAll(profilesDir, 0755); err != nil {
 		return fmt.Errorf("failed to create profiles directory: %w", err)
 	}
+	if err := os.MkdirAll(getMccConfigDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
 
 	// Check if default profile exists, if not, initialize from current .claude
 	defaultProfileDir := filepath.Join(profilesDir, defaultProfile)
@@ -184,13 +292,13 @@ func ensureMccStructure() error {
 			if err := copyDir(claudeDir, defaultProfileDir); err != nil {
 				return fmt.Errorf("failed to copy .claude to default profile: %w", err)
 			}
-			fmt.Println("Initialized default profile from existing ~/.claude")
+			notef("Initialized default profile from existing ~/.claude\n")
 		} else {
 			// Create empty default profile
 			if err := os.MkdirAll(defaultProfileDir, 0755); err != nil {
 				return fmt.Errorf("failed to create default profile: %w", err)
 			}
-			fmt.Println("Created empty default profile")
+			notef("Created empty default profile\n")
 		}
 	}
 
@@ -211,51 +319,28 @@ func ensureMccStructure() error {
 		}
 	}
 
-	// Check and update shell config hint
-	checkShellConfig()
-
 	return nil
 }
 
 func checkShellConfig() {
 	// Check if CLAUDE_CONFIG_DIR is already set correctly
-	claudeConfigDir := os.Getenv("CLAUDE_CONFIG_DIR")
-	expectedDir := getCurrentLink()
-
-	if claudeConfigDir != expectedDir {
-		fmt.Println("\n⚠️  To complete setup, add this to your ~/.zshrc or ~/.bashrc:")
-		fmt.Printf("   export CLAUDE_CONFIG_DIR=\"%s\"\n", expectedDir)
-		fmt.Println("   Then run: source ~/.zshrc (or restart your terminal)")
-		fmt.Println()
+	if os.Getenv("CLAUDE_CONFIG_DIR") == getCurrentLink() {
+		return
+	}
+	if shellBlockInstalled() {
+		notef("⚠️  CLAUDE_CONFIG_DIR isn't set in this shell yet; open a new terminal.\n\n")
+	} else {
+		notef("⚠️  To complete setup, run: mcc init\n\n")
 	}
 }
 
 func copyDir(src, dst string) error {
-	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		relPath, err := filepath.Rel(src, path)
-		if err != nil {
-			return err
-		}
-		dstPath := filepath.Join(dst, relPath)
-
-		if info.IsDir() {
-			return os.MkdirAll(dstPath, info.Mode())
-		}
-
-		// Copy file
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return err
-		}
-		return os.WriteFile(dstPath, data, info.Mode())
-	})
+	report := copyTree(src, dst, nil)
+	report.warn()
+	return report.err()
 }
 
-func copySettingsOnly(src, dst string) error {
+func copySettingsOnly(src, dst string, policy secretPolicy) error {
 	// List of settings files to copy (exclude credentials)
 	settingsFiles := []string{
 		"settings.json",
@@ -266,17 +351,31 @@ func copySettingsOnly(src, dst string) error {
 		return err
 	}
 
+	var findings []secretFinding
+	defer func() { reportSecrets(findings, policy) }()
+
 	for _, filename := range settingsFiles {
 		srcPath := filepath.Join(src, filename)
-		if _, err := os.Stat(srcPath); err == nil {
-			data, err := os.ReadFile(srcPath)
-			if err != nil {
-				continue
-			}
-			dstPath := filepath.Join(dst, filename)
-			if err := os.WriteFile(dstPath, data, 0644); err != nil {
+		info, err := os.Stat(srcPath)
+		if err != nil {
+			continue
+		}
+		dstPath := filepath.Join(dst, filename)
+		switch secretCheck(srcPath, filename, policy, &findings) {
+		case copyExclude:
+			continue
+		case copyRedacted:
+			if err := redactFile(srcPath, dstPath, info); err != nil {
 				return err
 			}
+			continue
+		}
+		data, err := os.ReadFile(srcPath)
+		if err != nil {
+			continue
+		}
+		if err := writeFileAtomic(dstPath, data, 0644); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -284,84 +383,187 @@ func copySettingsOnly(src, dst string) error {
 
 func switchProfile(name string, autoLaunch bool) error {
 	if !profileExists(name) {
-		return fmt.Errorf("profile '%s' does not exist. Use 'mcc new %s' to create it", name, name)
+		return withExitCode(exitProfileNotFound, fmt.Errorf("profile '%s' does not exist. Use 'mcc new %s' to create it", name, name))
 	}
 
-	currentLink := getCurrentLink()
 	profilePath := filepath.Join(getProfilesDir(), name)
+	if _, err := loadProfileMeta(profilePath); err != nil {
+		return err
+	}
+	if err := setCurrentProfile(name, profilePath); err != nil {
+		return err
+	}
+
+	notef("✓ Switched to profile: %s\n", name)
 
-	// Remove old symlink
-	if err := os.Remove(currentLink); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to remove old symlink: %w", err)
+	if autoLaunch {
+		extraEnv, err := prepareLaunch(profilePath)
+		if err != nil {
+			return err
+		}
+		return launchClaude(profilePath, extraEnv, nil)
 	}
+	return nil
+}
 
-	// Create new symlink
-	if err := os.Symlink(profilePath, currentLink); err != nil {
-		return fmt.Errorf("failed to create symlink: %w", err)
+// setCurrentProfile points the current symlink and config.json at a profile.
+func setCurrentProfile(name, profilePath string) error {
+	unlock, err := lockState()
+	if err != nil {
+		return err
 	}
+	defer unlock()
 
-	// Update config
-	config := &Config{CurrentProfile: name}
+	if err := replaceSymlink(profilePath, getCurrentLink()); err != nil {
+		return fmt.Errorf("failed to update current symlink: %w", err)
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	config.CurrentProfile = name
 	if err := saveConfig(config); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
+	return nil
+}
 
-	fmt.Printf("✓ Switched to profile: %s\n", name)
+// prepareLaunch readies a profile for launching claude and returns the
+// provider environment to launch it with.
+func prepareLaunch(profilePath string) ([]string, error) {
+	meta, err := loadProfileMeta(profilePath)
+	if err != nil {
+		return nil, err
+	}
+	extraEnv, err := getProviderEnv(meta)
+	if err != nil {
+		return nil, err
+	}
+	if meta.Color != "" {
+		extraEnv = append(extraEnv, "MCC_PROFILE_COLOR="+meta.Color)
+	}
+	if meta.Provider != "claude" {
+		if err := ensureOnboardingComplete(profilePath); err != nil {
+			return nil, err
+		}
+		notef("  Launching claude (provider: %s)...\n", meta.Provider)
+	} else {
+		notef("  Launching claude...\n")
+	}
+	return extraEnv, nil
+}
 
-	if autoLaunch {
-		meta := loadProfileMeta(profilePath)
-		extraEnv := getProviderEnv(meta)
-		if meta.Provider != "claude" {
-			ensureOnboardingComplete(profilePath)
-			fmt.Printf("  Launching claude (provider: %s)...\n", meta.Provider)
-		} else {
-			fmt.Println("  Launching claude...")
+// runProfile switches to a profile and launches claude in it with the
+// given arguments, either in place of mcc or, when supervised, as a child
+// process.
+func runProfile(name string, supervise bool, claudeArgs []string) error {
+	if err := switchProfile(name, false); err != nil {
+		return err
+	}
+	return launchProfile(name, supervise, claudeArgs)
+}
+
+// launchProfile launches claude in a profile without making it the current
+// profile.
+func launchProfile(name string, supervise bool, claudeArgs []string) error {
+	if supervise {
+		return superviseClaude(name, claudeArgs)
+	}
+	profilePath := filepath.Join(getProfilesDir(), name)
+	extraEnv, err := prepareLaunch(profilePath)
+	if err != nil {
+		return err
+	}
+	return launchClaude(profilePath, extraEnv, claudeArgs)
+}
+
+// exitLaunchError exits with claude's own exit status when it is known,
+// or reports err.
+func exitLaunchError(err error) {
+	var status claudeExitStatus
+	if errors.As(err, &status) {
+		exit(int(status))
+	}
+	fail(err)
+}
+
+func createProfile(name string, provider string, apiKey string, template string, policy secretPolicy) error {
+	meta := &ProfileMeta{Provider: provider, APIKey: apiKey}
+	var t *profileTemplate
+	if template != "" {
+		var err error
+		if t, err = findTemplate(template, nil); err != nil {
+			return err
+		}
+		t.fill(meta)
+	}
+	if meta.Provider == "" {
+		meta.Provider = "claude"
+	}
+	if err := initProfile(name, meta, t, policy); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Created profile: %s\n", name)
+	if t != nil {
+		fmt.Printf("  Template: %s\n", t.name)
+	}
+	if meta.Provider != "claude" {
+		fmt.Printf("  Provider: %s\n", meta.Provider)
+		if !meta.hasAPIKey() {
+			fmt.Fprintf(os.Stderr, "⚠️  The profile has no API key yet; set one with: mcc set-key %s <api-key>\n", name)
 		}
-		return launchClaude(profilePath, extraEnv)
 	}
+	fmt.Println()
+	fmt.Println("To use this profile:")
+	fmt.Printf("  mcc run %s\n", name)
 	return nil
 }
 
-func createProfile(name string, provider string, apiKey string) error {
+// initProfile creates a profile with the given metadata and the settings of
+// a template, or of the default profile when t is nil.
+func initProfile(name string, meta *ProfileMeta, t *profileTemplate, policy secretPolicy) error {
+	if !validProfileName(name) {
+		return usageError("invalid profile name '%s'", name)
+	}
 	if profileExists(name) {
 		return fmt.Errorf("profile '%s' already exists", name)
 	}
 
-	// Validate profile name
-	if strings.ContainsAny(name, "/\\:*?\"<>|") {
-		return fmt.Errorf("invalid profile name: contains forbidden characters")
-	}
-
 	profilePath := filepath.Join(getProfilesDir(), name)
 
-	// Copy settings from default profile (without credentials)
-	defaultProfileDir := filepath.Join(getProfilesDir(), defaultProfile)
-	if err := copySettingsOnly(defaultProfileDir, profilePath); err != nil {
-		// If copy fails, just create empty directory
+	if t != nil {
 		if err := os.MkdirAll(profilePath, 0755); err != nil {
 			return fmt.Errorf("failed to create profile directory: %w", err)
 		}
+		if t.dir != "" {
+			if _, _, err := syncSettings(t.dir, profilePath, policy); err != nil {
+				return fmt.Errorf("failed to copy template %s: %w", t.name, err)
+			}
+		}
+	} else {
+		// Copy settings from default profile (without credentials)
+		defaultProfileDir := filepath.Join(getProfilesDir(), defaultProfile)
+		if err := copySettingsOnly(defaultProfileDir, profilePath, policy); err != nil {
+			// If copy fails, just create empty directory
+			if err := os.MkdirAll(profilePath, 0755); err != nil {
+				return fmt.Errorf("failed to create profile directory: %w", err)
+			}
+		}
 	}
 
-	// Save profile metadata for non-claude providers
-	if provider != "" && provider != "claude" {
-		meta := &ProfileMeta{Provider: provider, APIKey: apiKey}
+	// Save profile metadata unless it's a plain claude profile
+	if !meta.isPlain() {
 		if err := saveProfileMeta(profilePath, meta); err != nil {
 			return fmt.Errorf("failed to save profile metadata: %w", err)
 		}
+	}
+	if meta.Provider != "claude" {
 		// Mark onboarding as completed so claude CLI doesn't prompt for login
 		if err := ensureOnboardingComplete(profilePath); err != nil {
 			return fmt.Errorf("failed to set onboarding flag: %w", err)
 		}
 	}
-
-	fmt.Printf("✓ Created profile: %s\n", name)
-	if provider != "" && provider != "claude" {
-		fmt.Printf("  Provider: %s\n", provider)
-	}
-	fmt.Println()
-	fmt.Println("To use this profile:")
-	fmt.Printf("  mcc run %s\n", name)
 	return nil
 }
 
@@ -371,7 +573,7 @@ func deleteProfile(name string) error {
 	}
 
 	if !profileExists(name) {
-		return fmt.Errorf("profile '%s' does not exist", name)
+		return profileNotFound(name)
 	}
 
 	// Check if it's current profile
@@ -395,11 +597,14 @@ func deleteProfile(name string) error {
 
 func setAPIKey(name string, apiKey string) error {
 	if !profileExists(name) {
-		return fmt.Errorf("profile '%s' does not exist", name)
+		return profileNotFound(name)
 	}
 
 	profilePath := filepath.Join(getProfilesDir(), name)
-	meta := loadProfileMeta(profilePath)
+	meta, err := loadProfileMeta(profilePath)
+	if err != nil {
+		return err
+	}
 
 	if meta.Provider == "claude" {
 		return fmt.Errorf("profile '%s' uses the claude provider and does not need an API key", name)
@@ -411,133 +616,158 @@ func setAPIKey(name string, apiKey string) error {
 	}
 
 	fmt.Printf("✓ Updated API key for profile: %s\n", name)
+	if meta.KeyEnv != "" || meta.KeyCommand != "" {
+		fmt.Fprintln(os.Stderr, "⚠️  The profile's key_env or key_command takes precedence over the stored key.")
+	}
 	return nil
 }
 
-func syncProfile(name string) error {
-	if !profileExists(name) {
-		return fmt.Errorf("profile '%s' does not exist. Use 'mcc new %s' to create it first", name, name)
+// syncSource returns the directory to sync from and a label for messages:
+// ~/.claude when from is empty, otherwise the named profile.
+func syncSource(from string) (string, string, error) {
+	if from != "" {
+		if !profileExists(from) {
+			return "", "", profileNotFound(from)
+		}
+		return filepath.Join(getProfilesDir(), from), "profile " + from, nil
 	}
 
 	claudeDir := getClaudeDir()
 	info, err := os.Stat(claudeDir)
 	if os.IsNotExist(err) {
-		return fmt.Errorf("~/.claude does not exist. Nothing to sync")
+		return "", "", fmt.Errorf("~/.claude does not exist. Nothing to sync")
 	}
 	if err != nil {
-		return fmt.Errorf("failed to access ~/.claude: %w", err)
+		return "", "", fmt.Errorf("failed to access ~/.claude: %w", err)
 	}
 	if !info.IsDir() {
-		return fmt.Errorf("~/.claude is not a directory")
+		return "", "", fmt.Errorf("~/.claude is not a directory")
 	}
 
 	// Check if ~/.claude is empty
 	entries, err := os.ReadDir(claudeDir)
 	if err != nil {
-		return fmt.Errorf("failed to read ~/.claude: %w", err)
+		return "", "", fmt.Errorf("failed to read ~/.claude: %w", err)
 	}
 	if len(entries) == 0 {
-		return fmt.Errorf("~/.claude is empty. Nothing to sync")
+		return "", "", fmt.Errorf("~/.claude is empty. Nothing to sync")
+	}
+	return claudeDir, "~/.claude", nil
+}
+
+func syncProfile(name string, from string, policy secretPolicy) error {
+	if !profileExists(name) {
+		return withExitCode(exitProfileNotFound, fmt.Errorf("profile '%s' does not exist. Use 'mcc new %s' to create it first", name, name))
+	}
+	if name == from {
+		return fmt.Errorf("cannot sync profile '%s' onto itself", name)
+	}
+
+	srcDir, label, err := syncSource(from)
+	if err != nil {
+		return err
 	}
 
 	profilePath := filepath.Join(getProfilesDir(), name)
 
-	// Copy settings from ~/.claude (excluding credentials)
-	count, skipped, err := syncSettings(claudeDir, profilePath)
+	// Copy settings (excluding credentials)
+	count, skipped, err := syncSettings(srcDir, profilePath, policy)
 	if err != nil {
 		return fmt.Errorf("failed to sync settings: %w", err)
 	}
 
 	if count == 0 {
-		fmt.Printf("⚠️  No settings files found in ~/.claude to sync\n")
+		fmt.Fprintf(os.Stderr, "⚠️  No settings files found in %s to sync\n", label)
 		if skipped > 0 {
-			fmt.Printf("   (%d credential file(s) were skipped)\n", skipped)
+			fmt.Fprintf(os.Stderr, "   (%d credential file(s) were skipped)\n", skipped)
 		}
 		return nil
 	}
 
-	fmt.Printf("✓ Synced %d file(s) from ~/.claude to profile: %s\n", count, name)
+	fmt.Printf("✓ Synced %d file(s) from %s to profile: %s\n", count, label, name)
 	if skipped > 0 {
 		fmt.Printf("  (%d credential file(s) were skipped for security)\n", skipped)
 	}
 	return nil
 }
 
-func syncSettings(src, dst string) (copied int, skipped int, err error) {
-	// Files/patterns to exclude (credentials and auth-related)
-	excludePatterns := []string{
-		".credentials.json",
-		"credentials.json",
-		"auth.json",
-		".auth",
-	}
+// Files/patterns never synced between profiles (credentials, auth-related
+// and per-account state)
+var syncExcludePatterns = []string{
+	".credentials.json",
+	"credentials.json",
+	"auth.json",
+	".auth",
+	".claude.json",
+	profileMetaFile,
+}
 
-	// Directories to skip entirely
-	skipDirs := []string{
-		".git",
-	}
+// Directories to skip entirely when syncing
+var syncSkipDirs = []string{
+	".git",
+}
 
-	isExcluded := func(name string) bool {
-		for _, pattern := range excludePatterns {
-			if strings.Contains(strings.ToLower(name), strings.ToLower(pattern)) {
-				return true
-			}
+func isSyncExcluded(name string) bool {
+	for _, pattern := range syncExcludePatterns {
+		if strings.Contains(strings.ToLower(name), strings.ToLower(pattern)) {
+			return true
 		}
-		return false
 	}
+	return false
+}
 
-	isSkipDir := func(name string) bool {
-		for _, dir := range skipDirs {
-			if name == dir {
-				return true
-			}
+func isSyncSkipDir(name string) bool {
+	for _, dir := range syncSkipDirs {
+		if name == dir {
+			return true
 		}
-		return false
 	}
+	return false
+}
 
-	err = filepath.Walk(src, func(path string, info os.FileInfo, walkErr error) error {
-		if walkErr != nil {
-			return walkErr
-		}
-
+func syncSettings(src, dst string, policy secretPolicy) (copied int, skipped int, err error) {
+	var findings []secretFinding
+	report := copyTree(src, dst, func(rel string, d fs.DirEntry) copyAction {
 		// Skip certain directories entirely
-		if info.IsDir() && isSkipDir(info.Name()) {
-			return filepath.SkipDir
+		if d.IsDir() && isSyncSkipDir(d.Name()) {
+			return copyExclude
 		}
-
-		relPath, relErr := filepath.Rel(src, path)
-		if relErr != nil {
-			return relErr
-		}
-
 		// Skip excluded files (credentials)
-		if isExcluded(info.Name()) {
-			if info.IsDir() {
-				return filepath.SkipDir
+		if isSyncExcluded(d.Name()) {
+			if !d.IsDir() {
+				skipped++
 			}
-			skipped++
-			return nil
+			return copyExclude
 		}
-
-		dstPath := filepath.Join(dst, relPath)
-
-		if info.IsDir() {
-			return os.MkdirAll(dstPath, info.Mode())
-		}
-
-		// Copy file
-		data, readErr := os.ReadFile(path)
-		if readErr != nil {
-			return readErr
-		}
-		if writeErr := os.WriteFile(dstPath, data, info.Mode()); writeErr != nil {
-			return writeErr
+		// Check file contents for keys and tokens
+		if d.Type().IsRegular() {
+			name := rel
+			if rel == "." {
+				name = filepath.Base(src)
+			}
+			return secretCheck(filepath.Join(src, rel), name, policy, &findings)
 		}
-		copied++
-		return nil
+		return copyInclude
 	})
+	report.warn()
+	reportSecrets(findings, policy)
 
-	return copied, skipped, err
+	return report.copied, skipped, report.err()
+}
+
+// profileProviderTag returns the provider shown after a profile's name in
+// listings, if it isn't claude, and the profile's details (see
+// profileDetails).
+func profileProviderTag(profile string) (string, string) {
+	meta, err := loadProfileMeta(filepath.Join(getProfilesDir(), profile))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+		return " [unreadable]", ""
+	}
+	if meta.Provider != "claude" {
+		return fmt.Sprintf(" [%s]", meta.Provider), profileDetails(meta)
+	}
+	return "", profileDetails(meta)
 }
 
 func showStatus() error {
@@ -557,19 +787,25 @@ func showStatus() error {
 	fmt.Println()
 	fmt.Println("Available profiles:")
 
+	limits, err := refreshRateLimits(profiles, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not check rate limits: %v\n", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 	for _, profile := range profiles {
-		profilePath := filepath.Join(getProfilesDir(), profile)
-		meta := loadProfileMeta(profilePath)
-		providerTag := ""
-		if meta.Provider != "claude" {
-			providerTag = fmt.Sprintf(" [%s]", meta.Provider)
+		providerTag, details := profileProviderTag(profile)
+		login := inspectLogin(profile).summary()
+		if limit, ok := limits[profile]; ok && limit.active(time.Now()) {
+			login += "  " + rateLimitSummary(limit)
 		}
 		if profile == config.CurrentProfile {
-			fmt.Printf("  * %s (active)%s\n", profile, providerTag)
+			fmt.Fprintf(w, "  * %s (active)%s\t%s\t%s\n", profile, providerTag, login, details)
 		} else {
-			fmt.Printf("    %s%s\n", profile, providerTag)
+			fmt.Fprintf(w, "    %s%s\t%s\t%s\n", profile, providerTag, login, details)
 		}
 	}
+	w.Flush()
 
 	// Check CLAUDE_CONFIG_DIR
 	claudeConfigDir := os.Getenv("CLAUDE_CONFIG_DIR")
@@ -594,24 +830,71 @@ func showHelp() {
 	fmt.Println("Claude Code Account Manager (mcc)")
 	fmt.Println()
 	fmt.Println("Usage:")
-	fmt.Println("  mcc                              Switch to default and launch claude")
+	fmt.Println("  mcc                              Switch to default (or the directory's profile) and launch")
 	fmt.Println("  mcc run <name>                   Switch to profile and launch claude")
+	fmt.Println("  mcc next (or mcc run --any)      Launch the first profile not rate limited")
+	fmt.Println("  mcc run --group <g>              Launch a group's profiles side by side in tmux")
+	fmt.Println("  mcc tmux <name>...               Launch profiles side by side in tmux")
+	fmt.Println("  mcc group [list]                 List profile groups")
+	fmt.Println("  mcc group create <g> <name>...   Create a group (--force replaces it)")
+	fmt.Println("  mcc group delete <g>             Delete a group")
+	fmt.Println("  mcc priority [name...]           Show or set the order 'mcc next' tries")
 	fmt.Println("  mcc new <name>                   Create a new claude profile")
 	fmt.Println("  mcc new <name> <provider> <key>  Create a profile with a provider")
+	fmt.Println("  mcc new <name> --template <t>    Create a profile from a template")
 	fmt.Println("  mcc set-key <name> <api-key>     Update API key for a profile")
-	fmt.Println("  mcc sync [name]                  Sync ~/.claude to profile (default: current)")
+	fmt.Println("  mcc sync [name...]               Sync ~/.claude to profile (default: current)")
+	fmt.Println("  mcc sync [name...] --watch       Keep syncing changes until Ctrl-C")
 	fmt.Println("  mcc status                       Show current status and profiles")
-	fmt.Println("  mcc list                         List all profiles")
+	fmt.Println("  mcc list [--tag t]               List profiles (with a tag) and when they were last used")
+	fmt.Println("  mcc whoami [name]                Show login and key status of a profile")
+	fmt.Println("  mcc sessions                     List sessions (--profile, --project, --grep)")
+	fmt.Println("  mcc sessions resume <id>         Resume a session in its profile and project")
+	fmt.Println("  mcc session move <id> --to <p>   Move a session to a profile (copy: keep it)")
+	fmt.Println("  mcc describe <name> [text]       Show or set a description (--owner o, --color c)")
+	fmt.Println("  mcc tag <name> [tag...]          Show or add tags (--remove to remove them)")
 	fmt.Println("  mcc delete <name>                Delete a profile")
+	fmt.Println("  mcc template [list]              List templates for new profiles")
+	fmt.Println("  mcc template save <p> <t>        Save a profile as a template, without credentials")
+	fmt.Println("  mcc template delete <t>          Delete a saved template")
+	fmt.Println("  mcc link [entry]                 Share entry across profiles (no entry: list)")
+	fmt.Println("  mcc unlink <entry>               Replace shared entry with a local copy")
+	fmt.Println("  mcc mcp list [name]              Compare MCP servers across profiles")
+	fmt.Println("  mcc mcp copy <from> <to>...      Copy MCP servers (--server a,b, --force)")
+	fmt.Println("  mcc mcp sync <from>              Make all profiles' MCP servers match <from>")
+	fmt.Println("  mcc usage [name] [--since 7d]    Token usage per profile and project (--json)")
+	fmt.Println("  mcc cost [name] [--since 30d]    Estimated spend per profile (--daily, --json)")
+	fmt.Println("  mcc history [name] [--since 7d]  Launches with directory and exit status (--json)")
+	fmt.Println("  mcc audit [--since 7d]           Changes made by mcc (--profile, --command, --json)")
+	fmt.Println("  mcc du [name]                    Disk use per profile and category")
+	fmt.Println("  mcc prune [--older-than 30d]     Delete old transcripts, history and caches (--dry-run)")
+	fmt.Println("  mcc plan [file]                  Show what it takes to match the manifest (mcc.toml)")
+	fmt.Println("  mcc apply [file]                 Create and change profiles to match the manifest")
+	fmt.Println("  mcc dump                         Print a manifest of the existing profiles")
+	fmt.Println("  mcc doctor [--fix]               Check the setup for problems and repair them")
 	fmt.Println("  mcc help                         Show this help message")
 	fmt.Println()
+	fmt.Println("  run and next accept --supervise to keep mcc running and offer failover.")
+	fmt.Println("  run accepts --no-switch to launch without changing the current profile.")
+	fmt.Println("  sync accepts --from <profile> to sync from a profile instead of ~/.claude.")
+	fmt.Println("  sync, new and template save accept --secrets refuse|redact|allow for files that contain keys.")
+	fmt.Println("  prune removes session transcripts, todos, file history, shell snapshots and caches;")
+	fmt.Println("  it accepts --profile <name> and never touches settings, credentials, plans or project memory.")
+	fmt.Println("  link and unlink accept --profiles a,b to limit them to some profiles.")
+	fmt.Println("  Every command accepts --quiet (or MCC_QUIET=1) to drop hints from stderr.")
+	fmt.Println()
 	fmt.Println("Providers:")
 	fmt.Println("  claude (default)  Standard Claude Code with Anthropic account")
 	fmt.Println("  kimi              Kimi Coding (uses claude CLI with Kimi API)")
 	fmt.Println()
 	fmt.Println("Setup:")
-	fmt.Println("  Add this to your ~/.zshrc or ~/.bashrc:")
-	fmt.Printf("  export CLAUDE_CONFIG_DIR=\"%s\"\n", getCurrentLink())
+	fmt.Println("  mcc init [--shell zsh|bash|fish]  Set CLAUDE_CONFIG_DIR in your shell rc file")
+	fmt.Println("  mcc uninstall [--restore-claude]  Remove it again (and copy default back to ~/.claude)")
+	fmt.Println("  mcc home                          Show where mcc keeps its files")
+	fmt.Println("  mcc home move <dir> | --xdg       Move them, e.g. to an encrypted volume")
+	fmt.Println()
+	fmt.Println("  mcc uses ~/.mcc unless MCC_HOME or --home <dir> name another directory, or")
+	fmt.Println("  MCC_XDG=1 asks for $XDG_CONFIG_HOME/mcc and $XDG_DATA_HOME/mcc.")
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  mcc                              # Launch with default profile")
@@ -619,28 +902,57 @@ func showHelp() {
 	fmt.Println("  mcc new work                     # Create a claude profile")
 	fmt.Println("  mcc new kimi-work kimi sk-xxx    # Create a Kimi profile")
 	fmt.Println("  mcc set-key kimi-work sk-new     # Update API key")
+	fmt.Println("  mcc link commands                # Share slash commands across all profiles")
+	fmt.Println("  mcc mcp copy default work        # Copy MCP servers to 'work'")
 	fmt.Println("  mcc status                       # Show all profiles")
 }
 
 func main() {
+	args, err := parseGlobalFlags(os.Args[1:])
+	if err != nil {
+		fail(err)
+	}
+
 	// Ensure mcc structure exists
 	if err := ensureMccStructure(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error initializing mcc: %v\n", err)
-		os.Exit(1)
+		os.Exit(exitCodeOf(err))
 	}
 
-	args := os.Args[1:]
-
 	// No args: switch to default and launch claude
 	if len(args) == 0 {
-		if err := switchProfile(defaultProfile, true); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+		checkShellConfig()
+		config, _ := loadConfig()
+		name := defaultProfile
+		if config != nil {
+			if profile := profileForDir(config); profile != "" {
+				name = profile
+			}
+		}
+		if err := runProfile(name, config != nil && config.Supervise, nil); err != nil {
+			exitLaunchError(err)
 		}
 		return
 	}
 
 	command := args[0]
+	switch command {
+	case "init", "uninstall", "doctor", "help", "-h", "--help":
+		// These deal with the shell setup themselves
+	default:
+		checkShellConfig()
+	}
+	// Held while the command changes mcc's files; commands that keep
+	// running afterwards release it
+	unlock := func() {}
+	if isMutation(args) {
+		var err error
+		if unlock, err = lockState(); err != nil {
+			fail(err)
+		}
+		persistUpgrades = true
+	}
+	startAudit(args)
 
 	switch command {
 	case "help", "-h", "--help":
@@ -648,114 +960,450 @@ func main() {
 
 	case "status", "st":
 		if err := showStatus(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			fail(err)
 		}
 
 	case "list", "ls":
+		parsed := parseArgs(args[1:])
 		profiles, err := listProfiles()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error listing profiles: %v\n", err)
-			os.Exit(1)
+			fail(fmt.Errorf("listing profiles: %w", err))
 		}
-		config, _ := loadConfig()
+		if parsed.has("tag") {
+			profiles = profilesWithTag(profiles, parsed.get("tag"))
+		}
+		config, err := loadConfig()
+		if err != nil {
+			fail(err)
+		}
+		used, err := lastUsed()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Could not read launch history: %v\n", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 		for _, profile := range profiles {
-			profilePath := filepath.Join(getProfilesDir(), profile)
-			meta := loadProfileMeta(profilePath)
-			providerTag := ""
-			if meta.Provider != "claude" {
-				providerTag = fmt.Sprintf(" [%s]", meta.Provider)
+			providerTag, details := profileProviderTag(profile)
+			lastUsedTag := "never used"
+			if t, ok := used[profile]; ok {
+				lastUsedTag = "last used " + formatUntil(t)
 			}
+			marker := " "
 			if profile == config.CurrentProfile {
-				fmt.Printf("* %s%s\n", profile, providerTag)
-			} else {
-				fmt.Printf("  %s%s\n", profile, providerTag)
+				marker = "*"
 			}
+			fmt.Fprintf(w, "%s %s%s\t%s\t%s\n", marker, profile, providerTag, lastUsedTag, details)
 		}
+		w.Flush()
 
 	case "new", "create", "add":
-		if len(args) < 2 {
-			fmt.Fprintln(os.Stderr, "Error: profile name required")
-			fmt.Fprintln(os.Stderr, "Usage: mcc new <name> [provider] [api-key]")
-			os.Exit(1)
+		parsed := parseArgs(args[1:])
+		if len(parsed.pos) < 1 {
+			failUsage("profile name required", "mcc new <name> [provider] [api-key] [--template t]")
 		}
-		name := args[1]
-		provider := ""
-		apiKey := ""
-		if len(args) >= 3 {
-			provider = args[2]
-		}
-		if len(args) >= 4 {
-			apiKey = args[3]
+		name := parsed.arg(0)
+		provider := parsed.arg(1)
+		apiKey := parsed.arg(2)
+		template := parsed.get("template")
+		if provider != "" && provider != "claude" && apiKey == "" && template == "" {
+			failUsage(fmt.Sprintf("API key required for provider '%s'", provider), fmt.Sprintf("mcc new <name> %s <api-key>", provider))
 		}
-		if provider != "" && provider != "claude" && apiKey == "" {
-			fmt.Fprintf(os.Stderr, "Error: API key required for provider '%s'\n", provider)
-			fmt.Fprintf(os.Stderr, "Usage: mcc new <name> %s <api-key>\n", provider)
-			os.Exit(1)
+		policy, err := parseSecretPolicy(parsed.get("secrets"))
+		if err != nil {
+			fail(err)
 		}
-		if err := createProfile(name, provider, apiKey); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+		if err := createProfile(name, provider, apiKey, template, policy); err != nil {
+			fail(err)
 		}
 
 	case "delete", "rm", "remove":
 		if len(args) < 2 {
-			fmt.Fprintln(os.Stderr, "Error: profile name required")
-			fmt.Fprintln(os.Stderr, "Usage: mcc delete <name>")
-			os.Exit(1)
+			failUsage("profile name required", "mcc delete <name>")
 		}
 		name := args[1]
 		if err := deleteProfile(name); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			fail(err)
 		}
 
 	case "sync":
-		var name string
-		if len(args) >= 2 {
-			name = args[1]
-		} else {
+		parsed := parseArgs(args[1:], "watch")
+		policy, err := parseSecretPolicy(parsed.get("secrets"))
+		if err != nil {
+			fail(err)
+		}
+		names := parsed.pos
+		if len(names) == 0 {
 			// Use current profile
 			config, err := loadConfig()
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
+				fail(err)
 			}
-			name = config.CurrentProfile
+			names = []string{config.CurrentProfile}
 		}
-		if err := syncProfile(name); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+		from := parsed.get("from")
+		for _, name := range names {
+			if err := syncProfile(name, from, policy); err != nil {
+				fail(err)
+			}
+		}
+		if parsed.has("watch") {
+			// watchSync locks for each pass instead
+			unlock()
+			if err := watchSync(names, from, policy); err != nil {
+				fail(err)
+			}
 		}
 
 	case "run":
-		var name string
-		if len(args) >= 2 {
-			name = args[1]
-		} else {
+		parsed := parseArgs(args[1:], "any", "supervise", "no-switch")
+		name := parsed.arg(0)
+		if parsed.has("group") {
+			profiles, err := groupProfiles(parsed.get("group"))
+			if err == nil {
+				err = launchTmux(profiles, parsed.get("group"))
+			}
+			if err != nil {
+				fail(err)
+			}
+			break
+		}
+		if parsed.has("any") {
+			next, err := nextAvailableProfile(nil)
+			if err != nil {
+				fail(err)
+			}
+			name = next
+		} else if name == "" {
+			if parsed.has("no-switch") {
+				failUsage("profile name required", "mcc run <name> --no-switch")
+			}
 			name = defaultProfile
 		}
-		if err := switchProfile(name, true); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+		config, _ := loadConfig()
+		supervise := parsed.has("supervise") || (config != nil && config.Supervise)
+		launch := runProfile
+		if parsed.has("no-switch") {
+			if _, err := resolveProfiles([]string{name}); err != nil {
+				fail(err)
+			}
+			launch = launchProfile
+		}
+		if err := launch(name, supervise, nil); err != nil {
+			exitLaunchError(err)
+		}
+
+	case "tmux":
+		parsed := parseArgs(args[1:])
+		if len(parsed.pos) == 0 {
+			failUsage("profile names required", "mcc tmux <profile>...")
+		}
+		if err := launchTmux(parsed.pos, ""); err != nil {
+			fail(err)
+		}
+
+	case "group", "groups":
+		parsed := parseArgs(args[1:], "force")
+		var err error
+		switch parsed.arg(0) {
+		case "", "list", "ls":
+			err = showGroups()
+		case "create":
+			if len(parsed.pos) < 3 {
+				failUsage("group name and profiles required", "mcc group create <group> <profile>... [--force]")
+			}
+			err = createGroup(parsed.arg(1), parsed.pos[2:], parsed.has("force"))
+		case "delete", "rm":
+			if len(parsed.pos) < 2 {
+				failUsage("group name required", "mcc group delete <group>")
+			}
+			err = deleteGroup(parsed.arg(1))
+		default:
+			failUsage(fmt.Sprintf("unknown group command '%s'", parsed.arg(0)), "mcc group list|create|delete")
+		}
+		if err != nil {
+			fail(err)
+		}
+
+	case "link":
+		parsed := parseArgs(args[1:])
+		var err error
+		if len(parsed.pos) == 0 {
+			err = showLinks()
+		} else {
+			err = linkEntry(parsed.arg(0), parsed.list("profiles"))
+		}
+		if err != nil {
+			fail(err)
+		}
+
+	case "unlink":
+		parsed := parseArgs(args[1:])
+		if len(parsed.pos) == 0 {
+			failUsage("entry name required", "mcc unlink <entry> [--profiles a,b]")
+		}
+		if err := unlinkEntry(parsed.arg(0), parsed.list("profiles")); err != nil {
+			fail(err)
+		}
+
+	case "mcp":
+		parsed := parseArgs(args[1:], "force", "prune")
+		var err error
+		switch parsed.arg(0) {
+		case "", "list", "ls":
+			err = listMCPServers(parsed.arg(1))
+		case "copy":
+			if len(parsed.pos) < 3 {
+				failUsage("source and target profiles required", "mcc mcp copy <from> <to>... [--server a,b] [--force]")
+			}
+			err = copyMCPServers(parsed.arg(1), parsed.pos[2:], parsed.list("server"), parsed.has("force"), false)
+		case "sync":
+			if len(parsed.pos) < 2 {
+				failUsage("source profile required", "mcc mcp sync <from> [--profiles a,b] [--prune]")
+			}
+			var targets []string
+			targets, err = resolveProfiles(parsed.list("profiles"))
+			if err == nil {
+				err = copyMCPServers(parsed.arg(1), targets, nil, true, parsed.has("prune"))
+			}
+		default:
+			failUsage(fmt.Sprintf("unknown mcp command '%s'", parsed.arg(0)), "mcc mcp list|copy|sync")
+		}
+		if err != nil {
+			fail(err)
+		}
+
+	case "usage":
+		parsed := parseArgs(args[1:], "json")
+		if err := showUsage(parsed.arg(0), parsed.get("since"), parsed.has("json")); err != nil {
+			fail(err)
+		}
+
+	case "cost":
+		parsed := parseArgs(args[1:], "daily", "json")
+		if err := showCost(parsed.arg(0), parsed.get("since"), parsed.has("daily"), parsed.has("json")); err != nil {
+			fail(err)
+		}
+
+	case "whoami":
+		if err := showWhoami(parseArgs(args[1:]).arg(0)); err != nil {
+			fail(err)
+		}
+
+	case "sessions", "session":
+		parsed := parseArgs(args[1:], "supervise", "force")
+		switch parsed.arg(0) {
+		case "move", "copy":
+			if parsed.arg(1) == "" || parsed.get("to") == "" {
+				failUsage("session ID and target profile required", fmt.Sprintf("mcc session %s <id> --to <profile> [--profile from] [--force]", parsed.arg(0)))
+			}
+			move := parsed.arg(0) == "move"
+			if err := moveSession(parsed.arg(1), parsed.get("profile"), parsed.get("to"), move, parsed.has("force")); err != nil {
+				fail(err)
+			}
+			finishAudit(0)
+			return
+		}
+		if parsed.arg(0) == "resume" {
+			if parsed.arg(1) == "" {
+				failUsage("session ID required", "mcc sessions resume <id> [--supervise]")
+			}
+			config, _ := loadConfig()
+			supervise := parsed.has("supervise") || (config != nil && config.Supervise)
+			if err := resumeSession(parsed.arg(1), parsed.get("profile"), supervise); err != nil {
+				exitLaunchError(err)
+			}
+			return
+		}
+		if parsed.arg(0) != "" {
+			failUsage(fmt.Sprintf("unknown sessions command '%s'", parsed.arg(0)), "mcc sessions [--profile p] [--project dir] [--grep text] | resume|move|copy <id>")
+		}
+		limit, err := parsed.count("limit", 50)
+		if err != nil {
+			fail(err)
+		}
+		filter := sessionFilter{
+			profile: parsed.get("profile"),
+			project: parsed.get("project"),
+			grep:    parsed.get("grep"),
+			limit:   limit,
+		}
+		if err := showSessions(filter); err != nil {
+			fail(err)
+		}
+
+	case "history":
+		parsed := parseArgs(args[1:], "json")
+		limit, err := parsed.count("limit", 20)
+		if err != nil {
+			fail(err)
+		}
+		if err := showHistory(parsed.arg(0), parsed.get("since"), limit, parsed.has("json")); err != nil {
+			fail(err)
+		}
+
+	case "audit":
+		parsed := parseArgs(args[1:], "json")
+		limit, err := parsed.count("limit", 50)
+		if err != nil {
+			fail(err)
+		}
+		filter := auditFilter{
+			since:   parsed.get("since"),
+			profile: parsed.get("profile"),
+			command: parsed.get("command"),
+			limit:   limit,
+		}
+		if err := showAudit(filter, parsed.has("json")); err != nil {
+			fail(err)
+		}
+
+	case "du":
+		if err := showDiskUsage(parseArgs(args[1:]).arg(0)); err != nil {
+			fail(err)
+		}
+
+	case "prune":
+		parsed := parseArgs(args[1:], "dry-run")
+		olderThan := parsed.get("older-than")
+		if olderThan == "" {
+			olderThan = "30d"
+		}
+		if err := pruneProfiles(parsed.get("profile"), olderThan, parsed.has("dry-run")); err != nil {
+			fail(err)
+		}
+
+	case "next":
+		parsed := parseArgs(args[1:], "supervise")
+		name, err := nextAvailableProfile(nil)
+		if err != nil {
+			fail(err)
+		}
+		config, _ := loadConfig()
+		supervise := parsed.has("supervise") || (config != nil && config.Supervise)
+		if err := runProfile(name, supervise, nil); err != nil {
+			exitLaunchError(err)
+		}
+
+	case "priority":
+		var err error
+		if len(args) < 2 {
+			err = showPriority()
+		} else {
+			err = setPriority(args[1:])
+		}
+		if err != nil {
+			fail(err)
+		}
+
+	case "init":
+		if err := initShell(parseArgs(args[1:]).get("shell")); err != nil {
+			fail(err)
+		}
+
+	case "uninstall":
+		if err := uninstallShell(parseArgs(args[1:], "restore-claude").has("restore-claude")); err != nil {
+			fail(err)
+		}
+
+	case "doctor":
+		if err := runDoctor(parseArgs(args[1:], "fix").has("fix")); err != nil {
+			fail(err)
+		}
+
+	case "home":
+		parsed := parseArgs(args[1:], "xdg", "force")
+		switch parsed.arg(0) {
+		case "":
+			showHome()
+		case "move":
+			if err := moveHome(parsed.arg(1), parsed.has("xdg"), parsed.has("force")); err != nil {
+				fail(err)
+			}
+		default:
+			failUsage(fmt.Sprintf("unknown home command '%s'", parsed.arg(0)), "mcc home [move <dir> | move --xdg]")
+		}
+
+	case "template", "templates":
+		parsed := parseArgs(args[1:], "force")
+		var err error
+		switch parsed.arg(0) {
+		case "", "list", "ls":
+			err = listTemplates()
+		case "save":
+			if len(parsed.pos) < 3 {
+				failUsage("profile and template name required", "mcc template save <profile> <template> [--force] [--secrets refuse|redact|allow]")
+			}
+			var policy secretPolicy
+			if policy, err = parseSecretPolicy(parsed.get("secrets")); err == nil {
+				err = saveTemplate(parsed.arg(1), parsed.arg(2), parsed.has("force"), policy)
+			}
+		case "delete", "rm":
+			if len(parsed.pos) < 2 {
+				failUsage("template name required", "mcc template delete <template>")
+			}
+			err = deleteTemplate(parsed.arg(1))
+		default:
+			failUsage(fmt.Sprintf("unknown template command '%s'", parsed.arg(0)), "mcc template list|save|delete")
+		}
+		if err != nil {
+			fail(err)
+		}
+
+	case "describe":
+		parsed := parseArgs(args[1:])
+		if len(parsed.pos) < 1 {
+			failUsage("profile name required", "mcc describe <name> [description] [--owner o] [--color c]")
+		}
+		var description, owner, color *string
+		if len(parsed.pos) > 1 {
+			text := strings.Join(parsed.pos[1:], " ")
+			description = &text
+		}
+		if parsed.has("owner") {
+			value := parsed.get("owner")
+			owner = &value
+		}
+		if parsed.has("color") {
+			value := parsed.get("color")
+			color = &value
+		}
+		if err := describeProfile(parsed.arg(0), description, owner, color); err != nil {
+			fail(err)
+		}
+
+	case "tag":
+		parsed := parseArgs(args[1:], "remove")
+		if len(parsed.pos) < 1 {
+			failUsage("profile name required", "mcc tag <name> [tag...] [--remove]")
+		}
+		if err := tagProfile(parsed.arg(0), parsed.pos[1:], parsed.has("remove")); err != nil {
+			fail(err)
+		}
+
+	case "plan", "apply":
+		if err := applyManifest(parseArgs(args[1:]).arg(0), command == "apply"); err != nil {
+			fail(err)
+		}
+
+	case "dump":
+		if err := dumpManifest(os.Stdout); err != nil {
+			fail(err)
 		}
 
 	case "set-key":
 		if len(args) < 3 {
-			fmt.Fprintln(os.Stderr, "Error: profile name and API key required")
-			fmt.Fprintln(os.Stderr, "Usage: mcc set-key <name> <api-key>")
-			os.Exit(1)
+			failUsage("profile name and API key required", "mcc set-key <name> <api-key>")
 		}
 		name := args[1]
 		apiKey := args[2]
 		if err := setAPIKey(name, apiKey); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			fail(err)
 		}
 
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
 		fmt.Fprintln(os.Stderr, "Run 'mcc help' for usage")
-		os.Exit(1)
+		exit(exitUsage)
 	}
+
+	finishAudit(0)
 }
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfileNames(t *testing.T) {
+	useTempHome(t)
+	if err := os.MkdirAll(filepath.Join(getProfilesDir(), "work"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		valid  bool
+		exists bool
+	}{
+		{"work", true, true},
+		{"home", true, false},
+		{"", false, false},
+		{".", false, false},
+		{"..", false, false},
+		{"work/..", false, false},
+		{"../profiles/work", false, false},
+	}
+	for _, tt := range tests {
+		if got := validProfileName(tt.name); got != tt.valid {
+			t.Errorf("validProfileName(%q) = %v, want %v", tt.name, got, tt.valid)
+		}
+		if got := profileExists(tt.name); got != tt.exists {
+			t.Errorf("profileExists(%q) = %v, want %v", tt.name, got, tt.exists)
+		}
+		if _, err := resolveProfiles([]string{tt.name}); (err == nil) != tt.exists {
+			t.Errorf("resolveProfiles(%q) error = %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,630 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const manifestFileName = "mcc.toml"
+
+// manifestProfile is a [profiles.<name>] table of the manifest. API keys
+// are never part of it: a profile names where to get its key instead.
+type manifestProfile struct {
+	name     string
+	meta     ProfileMeta // provider, base URL, model and key source
+	template string      // template the profile is created from, or ""
+	shared   []string    // entries linked into the shared directory
+	dirs     []string    // absolute directories plain 'mcc' launches it in
+}
+
+type manifest struct {
+	path      string
+	profiles  []manifestProfile // sorted by name
+	templates map[string]*profileTemplate
+}
+
+func getManifestPath() string {
+	return filepath.Join(getMccConfigDir(), manifestFileName)
+}
+
+// expandDir makes a manifest directory absolute: ~ is the home directory
+// and relative paths are taken from the manifest's own directory.
+func expandDir(dir, base string) (string, error) {
+	if dir == "~" || strings.HasPrefix(dir, "~/") || strings.HasPrefix(dir, "~"+string(filepath.Separator)) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, dir[1:])
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(base, dir)
+	}
+	return filepath.Clean(dir), nil
+}
+
+// decodeManifestMeta sets the ProfileMeta field a manifest key names and
+// reports whether key was one of them.
+func decodeManifestMeta(meta *ProfileMeta, key string, value any) (bool, error) {
+	var field *string
+	switch key {
+	case "provider":
+		field = &meta.Provider
+	case "base_url":
+		field = &meta.BaseURL
+	case "model":
+		field = &meta.Model
+	case "key_env":
+		field = &meta.KeyEnv
+	case "key_command":
+		field = &meta.KeyCommand
+	case "description":
+		field = &meta.Description
+	case "color":
+		field = &meta.Color
+	case "owner":
+		field = &meta.Owner
+	case "tags":
+		list, ok := value.([]any)
+		if !ok {
+			return true, fmt.Errorf("should be an array of strings")
+		}
+		meta.Tags = nil
+		for _, item := range list {
+			tag, ok := item.(string)
+			if !ok {
+				return true, fmt.Errorf("should be an array of strings")
+			}
+			if err := validateTag(tag); err != nil {
+				return true, err
+			}
+			if !containsString(meta.Tags, tag) {
+				meta.Tags = append(meta.Tags, tag)
+			}
+		}
+		sort.Strings(meta.Tags)
+		return true, nil
+	case "api_key":
+		return true, fmt.Errorf("keys don't belong in the manifest; use key_env or key_command")
+	default:
+		return false, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return true, fmt.Errorf("should be a string")
+	}
+	if key == "provider" && !containsString(knownProviders, s) {
+		return true, fmt.Errorf("unknown provider '%s' (use one of: %s)", s, strings.Join(knownProviders, ", "))
+	}
+	if key == "color" {
+		if err := validateColor(s); err != nil {
+			return true, err
+		}
+	}
+	*field = s
+	return true, nil
+}
+
+// manifestTables returns the [<section>.<name>] tables of a manifest.
+func manifestTables(doc map[string]any, section string) (map[string]map[string]any, error) {
+	raw, ok := doc[section]
+	if !ok {
+		return nil, nil
+	}
+	sub, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s should be a table of [%s.<name>] tables", section, section)
+	}
+	tables := make(map[string]map[string]any)
+	for name, raw := range sub {
+		table, ok := raw.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s.%s should be a table", section, name)
+		}
+		tables[name] = table
+	}
+	return tables, nil
+}
+
+// loadManifest reads and checks a manifest, by default the one in mcc's
+// config directory.
+func loadManifest(path string) (*manifest, error) {
+	if path == "" {
+		path = getManifestPath()
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return nil, usageError("YAML manifests aren't supported; write %s in TOML (mcc dump prints one)", path)
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && path == getManifestPath() {
+		return nil, fmt.Errorf("no manifest at %s; start one with: mcc dump > %s", path, shortenHome(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+	invalid := func(format string, a ...any) error {
+		return withExitCode(exitDataError, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, a...)))
+	}
+	doc, err := parseTOML(string(data))
+	if err != nil {
+		return nil, invalid("%v", err)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	m := &manifest{path: abs, templates: make(map[string]*profileTemplate)}
+
+	for key := range doc {
+		if key != "profiles" && key != "templates" {
+			return nil, invalid("unknown key '%s'", key)
+		}
+	}
+	profiles, err := manifestTables(doc, "profiles")
+	if err != nil {
+		return nil, invalid("%v", err)
+	}
+	templates, err := manifestTables(doc, "templates")
+	if err != nil {
+		return nil, invalid("%v", err)
+	}
+
+	for name, table := range templates {
+		if err := validateTemplateName(name); err != nil {
+			return nil, invalid("%v", err)
+		}
+		t := &profileTemplate{name: name, source: shortenHome(abs)}
+		for key, value := range table {
+			field := fmt.Sprintf("templates.%s.%s", name, key)
+			if known, err := decodeManifestMeta(&t.meta, key, value); known {
+				if err != nil {
+					return nil, invalid("%s: %v", field, err)
+				}
+				continue
+			}
+			if key != "files" {
+				return nil, invalid("unknown key '%s'", field)
+			}
+			dir, ok := value.(string)
+			if !ok {
+				return nil, invalid("%s: should be a string", field)
+			}
+			if t.dir, err = expandDir(dir, filepath.Dir(abs)); err != nil {
+				return nil, err
+			}
+		}
+		if t.meta.Provider == "" {
+			t.meta.Provider = "claude"
+		}
+		m.templates[name] = t
+	}
+
+	dirOwner := make(map[string]string)
+	for name, table := range profiles {
+		if !validProfileName(name) {
+			return nil, invalid("invalid profile name '%s'", name)
+		}
+		p := manifestProfile{name: name}
+		for key, value := range table {
+			field := fmt.Sprintf("profiles.%s.%s", name, key)
+			if known, err := decodeManifestMeta(&p.meta, key, value); known {
+				if err != nil {
+					return nil, invalid("%s: %v", field, err)
+				}
+				continue
+			}
+			switch key {
+			case "template":
+				s, ok := value.(string)
+				if !ok {
+					return nil, invalid("%s: should be a string", field)
+				}
+				p.template = s
+			case "shared", "dirs":
+				list, ok := value.([]any)
+				if !ok {
+					return nil, invalid("%s: should be an array of strings", field)
+				}
+				for _, item := range list {
+					s, ok := item.(string)
+					if !ok {
+						return nil, invalid("%s: should be an array of strings", field)
+					}
+					if key == "shared" {
+						if err := validateSharedEntry(s); err != nil {
+							return nil, invalid("%s: %v", field, err)
+						}
+						if !containsString(p.shared, s) {
+							p.shared = append(p.shared, s)
+						}
+						continue
+					}
+					dir, err := expandDir(s, filepath.Dir(abs))
+					if err != nil {
+						return nil, err
+					}
+					if owner, taken := dirOwner[dir]; taken && owner != name {
+						return nil, invalid("%s: %s is also a directory of profile '%s'", field, s, owner)
+					}
+					if _, taken := dirOwner[dir]; !taken {
+						dirOwner[dir] = name
+						p.dirs = append(p.dirs, dir)
+					}
+				}
+			default:
+				return nil, invalid("unknown key '%s'", field)
+			}
+		}
+		if p.template != "" {
+			t, err := findTemplate(p.template, m)
+			if err != nil {
+				return nil, invalid("profiles.%s.template: %v", name, err)
+			}
+			t.fill(&p.meta)
+		}
+		if p.meta.Provider == "" {
+			p.meta.Provider = "claude"
+		}
+		if p.meta.KeyEnv != "" && p.meta.KeyCommand != "" {
+			return nil, invalid("profiles.%s: set key_env or key_command, not both", name)
+		}
+		sort.Strings(p.shared)
+		m.profiles = append(m.profiles, p)
+	}
+	sort.Slice(m.profiles, func(i, j int) bool { return m.profiles[i].name < m.profiles[j].name })
+	return m, nil
+}
+
+// manifestChange is one step of bringing the profiles in line with a
+// manifest.
+type manifestChange struct {
+	mark  string // + adds, ~ changes, - removes
+	desc  string
+	apply func() error
+}
+
+// planManifest works out the changes that make the profiles match m.
+// Profiles the manifest doesn't mention are left alone.
+func planManifest(m *manifest) ([]manifestChange, error) {
+	var changes []manifestChange
+	sharedEntries, err := os.ReadDir(getSharedDir())
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	for _, p := range m.profiles {
+		p := p
+		profilePath := filepath.Join(getProfilesDir(), p.name)
+		if !profileExists(p.name) {
+			desc := "create profile " + p.name
+			if p.meta.Provider != "claude" {
+				desc += fmt.Sprintf(" (provider: %s)", p.meta.Provider)
+			}
+			var t *profileTemplate
+			if p.template != "" {
+				var err error
+				if t, err = findTemplate(p.template, m); err != nil {
+					return nil, err
+				}
+				desc += fmt.Sprintf(" from template %s", p.template)
+			}
+			changes = append(changes, manifestChange{"+", desc, func() error {
+				meta := p.meta
+				if err := initProfile(p.name, &meta, t, secretsRefuse); err != nil {
+					return err
+				}
+				fmt.Printf("✓ Created profile: %s\n", p.name)
+				return nil
+			}})
+		} else {
+			current, err := loadProfileMeta(profilePath)
+			if err != nil {
+				return nil, err
+			}
+			if diff := metaDiff(current, &p.meta); len(diff) > 0 {
+				changes = append(changes, manifestChange{"~", fmt.Sprintf("update profile %s: %s", p.name, strings.Join(diff, ", ")), func() error {
+					return applyManifestMeta(p.name, &p.meta)
+				}})
+			}
+		}
+
+		for _, entry := range p.shared {
+			if isSharedLink(profilePath, entry) {
+				continue
+			}
+			entry := entry
+			changes = append(changes, manifestChange{"+", fmt.Sprintf("link %s in profile %s", entry, p.name), func() error {
+				return linkEntry(entry, []string{p.name})
+			}})
+		}
+		for _, e := range sharedEntries {
+			entry := e.Name()
+			if containsString(p.shared, entry) || !isSharedLink(profilePath, entry) {
+				continue
+			}
+			changes = append(changes, manifestChange{"-", fmt.Sprintf("unlink %s in profile %s", entry, p.name), func() error {
+				return unlinkEntry(entry, []string{p.name})
+			}})
+		}
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	rules := manifestDirRules(m, config.Directories)
+	if !sameDirRules(rules, config.Directories) {
+		var desc []string
+		for _, rule := range rules {
+			desc = append(desc, fmt.Sprintf("%s → %s", shortenHome(rule.Path), rule.Profile))
+		}
+		if len(desc) == 0 {
+			desc = []string{"none"}
+		}
+		changes = append(changes, manifestChange{"~", "set directory rules: " + strings.Join(desc, ", "), func() error {
+			config, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			config.Directories = rules
+			if err := saveConfig(config); err != nil {
+				return fmt.Errorf("failed to save config: %w", err)
+			}
+			fmt.Println("✓ Set directory rules")
+			return nil
+		}})
+	}
+	return changes, nil
+}
+
+// metaDiff lists the manifest fields in which want differs from current.
+func metaDiff(current, want *ProfileMeta) []string {
+	var diff []string
+	field := func(name, from, to string) {
+		if from == to {
+			return
+		}
+		if from == "" {
+			from = "(unset)"
+		}
+		if to == "" {
+			to = "(unset)"
+		}
+		diff = append(diff, fmt.Sprintf("%s %s → %s", name, from, to))
+	}
+	field("provider", current.Provider, want.Provider)
+	field("base_url", current.BaseURL, want.BaseURL)
+	field("model", current.Model, want.Model)
+	field("key_env", current.KeyEnv, want.KeyEnv)
+	field("key_command", current.KeyCommand, want.KeyCommand)
+	field("description", current.Description, want.Description)
+	field("tags", strings.Join(current.Tags, ","), strings.Join(want.Tags, ","))
+	field("color", current.Color, want.Color)
+	field("owner", current.Owner, want.Owner)
+	return diff
+}
+
+// applyManifestMeta sets a profile's manifest fields, keeping its stored
+// API key.
+func applyManifestMeta(name string, want *ProfileMeta) error {
+	profilePath := filepath.Join(getProfilesDir(), name)
+	meta, err := loadProfileMeta(profilePath)
+	if err != nil {
+		return err
+	}
+	meta.Provider = want.Provider
+	meta.BaseURL = want.BaseURL
+	meta.Model = want.Model
+	meta.KeyEnv = want.KeyEnv
+	meta.KeyCommand = want.KeyCommand
+	meta.Description = want.Description
+	meta.Tags = want.Tags
+	meta.Color = want.Color
+	meta.Owner = want.Owner
+	if err := saveProfileMeta(profilePath, meta); err != nil {
+		return fmt.Errorf("failed to save profile metadata: %w", err)
+	}
+	if meta.Provider != "claude" {
+		if err := ensureOnboardingComplete(profilePath); err != nil {
+			return fmt.Errorf("failed to set onboarding flag: %w", err)
+		}
+	}
+	fmt.Printf("✓ Updated profile: %s\n", name)
+	return nil
+}
+
+// manifestDirRules returns the directory rules of m, keeping the current
+// rules of profiles it doesn't mention, sorted by path.
+func manifestDirRules(m *manifest, current []dirRule) []dirRule {
+	mentioned := make(map[string]bool)
+	var rules []dirRule
+	for _, p := range m.profiles {
+		mentioned[p.name] = true
+		for _, dir := range p.dirs {
+			rules = append(rules, dirRule{Path: dir, Profile: p.name})
+		}
+	}
+	for _, rule := range current {
+		if !mentioned[rule.Profile] {
+			rules = append(rules, rule)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Path < rules[j].Path })
+	return rules
+}
+
+// sameDirRules reports whether a, sorted by path, holds the rules of b in
+// any order.
+func sameDirRules(a, b []dirRule) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	b = append([]dirRule(nil), b...)
+	sort.Slice(b, func(i, j int) bool { return b[i].Path < b[j].Path })
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// profileForDir returns the profile plain 'mcc' launches in the working
+// directory: the one whose directory rule is its closest parent, or "".
+func profileForDir(config *Config) string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	best, bestLen := "", -1
+	for _, rule := range config.Directories {
+		if cwd != rule.Path && !strings.HasPrefix(cwd, strings.TrimSuffix(rule.Path, string(filepath.Separator))+string(filepath.Separator)) {
+			continue
+		}
+		if len(rule.Path) > bestLen && profileExists(rule.Profile) {
+			best, bestLen = rule.Profile, len(rule.Path)
+		}
+	}
+	return best
+}
+
+// applyManifest prints the changes that make the profiles match the
+// manifest at path, and makes them when apply is set.
+func applyManifest(path string, apply bool) error {
+	m, err := loadManifest(path)
+	if err != nil {
+		return err
+	}
+	changes, err := planManifest(m)
+	if err != nil {
+		return err
+	}
+
+	var unmanaged []string
+	profiles, err := listProfiles()
+	if err != nil {
+		return err
+	}
+	for _, profile := range profiles {
+		if !m.has(profile) {
+			unmanaged = append(unmanaged, profile)
+		}
+	}
+
+	if len(changes) == 0 {
+		fmt.Printf("✓ Profiles match %s\n", shortenHome(m.path))
+	} else if !apply {
+		fmt.Printf("Changes to match %s:\n", shortenHome(m.path))
+		for _, change := range changes {
+			fmt.Printf("  %s %s\n", change.mark, change.desc)
+		}
+		notef("Run 'mcc apply' to make them.\n")
+	} else {
+		for i, change := range changes {
+			if err := change.apply(); err != nil {
+				if i > 0 {
+					notef("Made %d of %d changes.\n", i, len(changes))
+				}
+				return fmt.Errorf("%s: %w", change.desc, err)
+			}
+		}
+	}
+	if len(unmanaged) > 0 {
+		notef("  Not in the manifest, left alone: %s\n", strings.Join(unmanaged, ", "))
+	}
+
+	for _, p := range m.profiles {
+		if p.meta.Provider == "claude" || p.meta.KeyEnv != "" || p.meta.KeyCommand != "" {
+			continue
+		}
+		if meta, err := loadProfileMeta(filepath.Join(getProfilesDir(), p.name)); err == nil && meta.APIKey != "" {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "⚠️  Profile '%s' has no API key; set key_env or key_command, or run: mcc set-key %s <api-key>\n", p.name, p.name)
+	}
+	return nil
+}
+
+func (m *manifest) has(name string) bool {
+	for _, p := range m.profiles {
+		if p.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// dumpManifest writes a manifest describing the existing profiles to w.
+func dumpManifest(w io.Writer) error {
+	profiles, err := listProfiles()
+	if err != nil {
+		return err
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	sharedEntries, err := os.ReadDir(getSharedDir())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# mcc profiles, written by mcc dump. Check with mcc plan, apply with mcc apply.\n")
+	fmt.Fprintf(&b, "# API keys are never written here: give a profile key_env or key_command.\n")
+	for _, profile := range profiles {
+		profilePath := filepath.Join(getProfilesDir(), profile)
+		meta, err := loadProfileMeta(profilePath)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "\n[profiles.%s]\n", tomlKey(profile))
+		fmt.Fprintf(&b, "provider = %s\n", tomlString(meta.Provider))
+		for _, field := range [][2]string{
+			{"base_url", meta.BaseURL},
+			{"model", meta.Model},
+			{"key_env", meta.KeyEnv},
+			{"key_command", meta.KeyCommand},
+			{"description", meta.Description},
+			{"color", meta.Color},
+			{"owner", meta.Owner},
+		} {
+			if field[1] != "" {
+				fmt.Fprintf(&b, "%s = %s\n", field[0], tomlString(field[1]))
+			}
+		}
+		if len(meta.Tags) > 0 {
+			fmt.Fprintf(&b, "tags = %s\n", tomlStrings(meta.Tags))
+		}
+		if meta.APIKey != "" && meta.KeyEnv == "" && meta.KeyCommand == "" {
+			fmt.Fprintf(&b, "# uses a key stored with mcc set-key, which stays out of the manifest\n")
+		}
+
+		var shared []string
+		for _, e := range sharedEntries {
+			if isSharedLink(profilePath, e.Name()) {
+				shared = append(shared, e.Name())
+			}
+		}
+		if len(shared) > 0 {
+			fmt.Fprintf(&b, "shared = %s\n", tomlStrings(shared))
+		}
+
+		var dirs []string
+		for _, rule := range config.Directories {
+			if rule.Profile == profile {
+				dirs = append(dirs, filepath.ToSlash(shortenHome(rule.Path)))
+			}
+		}
+		if len(dirs) > 0 {
+			fmt.Fprintf(&b, "dirs = %s\n", tomlStrings(dirs))
+		}
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
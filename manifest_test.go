@@ -0,0 +1,239 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setupManifestHome gives a test an mcc home with the given profiles.
+func setupManifestHome(t *testing.T, profiles map[string]ProfileMeta) string {
+	t.Helper()
+	home := useTempHome(t)
+	if err := ensureMccStructure(); err != nil {
+		t.Fatal(err)
+	}
+	for name, meta := range profiles {
+		profilePath := filepath.Join(getProfilesDir(), name)
+		if err := os.MkdirAll(profilePath, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := saveProfileMeta(profilePath, &meta); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return home
+}
+
+func writeManifest(t *testing.T, src string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), manifestFileName)
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadManifest(t *testing.T) {
+	home := setupManifestHome(t, nil)
+	path := writeManifest(t, `
+[templates.kimi]
+provider = "kimi"
+model = "kimi-k2"
+key_env = "KIMI_KEY"
+tags = ["cn"]
+
+[profiles.work]
+model = "opus"
+shared = ["commands", "agents", "commands"]
+dirs = ["~/code/work", "rel", "~/code/work"]
+tags = ["b", "a", "b"]
+
+[profiles.cheap]
+template = "kimi"
+model = "kimi-latest"
+`)
+	m, err := loadManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.profiles) != 2 || m.profiles[0].name != "cheap" || m.profiles[1].name != "work" {
+		t.Fatalf("profiles = %+v", m.profiles)
+	}
+
+	cheap := m.profiles[0]
+	wantCheap := ProfileMeta{Provider: "kimi", Model: "kimi-latest", KeyEnv: "KIMI_KEY", Tags: []string{"cn"}}
+	if !reflect.DeepEqual(cheap.meta, wantCheap) || cheap.template != "kimi" {
+		t.Errorf("cheap = %+v, want meta %+v from template kimi", cheap, wantCheap)
+	}
+
+	work := m.profiles[1]
+	wantWork := ProfileMeta{Provider: "claude", Model: "opus", Tags: []string{"a", "b"}}
+	if !reflect.DeepEqual(work.meta, wantWork) {
+		t.Errorf("work meta = %+v, want %+v", work.meta, wantWork)
+	}
+	if want := []string{"agents", "commands"}; !reflect.DeepEqual(work.shared, want) {
+		t.Errorf("work shared = %v, want %v", work.shared, want)
+	}
+	wantDirs := []string{filepath.Join(home, "code", "work"), filepath.Join(filepath.Dir(path), "rel")}
+	if !reflect.DeepEqual(work.dirs, wantDirs) {
+		t.Errorf("work dirs = %v, want %v", work.dirs, wantDirs)
+	}
+}
+
+func TestLoadManifestErrors(t *testing.T) {
+	setupManifestHome(t, nil)
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"syntax", "[profiles.work\n", "line 1: expected ] after table name"},
+		{"unknown section", "[other]\n", "unknown key 'other'"},
+		{"unknown key", "[profiles.work]\nmodle = \"x\"\n", "unknown key 'profiles.work.modle'"},
+		{"api key", "[profiles.work]\napi_key = \"sk\"\n", "keys don't belong in the manifest"},
+		{"provider", "[profiles.work]\nprovider = \"nope\"\n", "unknown provider 'nope'"},
+		{"wrong type", "[profiles.work]\nmodel = 1\n", "profiles.work.model: should be a string"},
+		{"not a table", "profiles = 1\n", "profiles should be a table"},
+		{"invalid name", "[profiles.\"..\"]\n", "invalid profile name '..'"},
+		{"both key sources", "[profiles.work]\nkey_env = \"A\"\nkey_command = \"b\"\n", "set key_env or key_command, not both"},
+		{"dir taken", "[profiles.a]\ndirs = [\"/x\"]\n[profiles.b]\ndirs = [\"/x\"]\n", "is also a directory of profile"},
+		{"shared state", "[profiles.work]\nshared = [\".credentials.json\"]\n", "cannot be shared"},
+		{"missing template", "[profiles.work]\ntemplate = \"nope\"\n", "template 'nope' does not exist"},
+		{"bad color", "[profiles.work]\ncolor = \"mauve\"\n", "invalid color 'mauve'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadManifest(writeManifest(t, tt.src))
+			if err == nil {
+				t.Fatalf("loadManifest succeeded, want error %q", tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %q, want %q", err, tt.want)
+			}
+			if exitCodeOf(err) != exitDataError {
+				t.Errorf("exit code = %d, want %d", exitCodeOf(err), exitDataError)
+			}
+		})
+	}
+
+	if _, err := loadManifest(filepath.Join(t.TempDir(), "mcc.yaml")); exitCodeOf(err) != exitUsage {
+		t.Errorf("YAML manifest: error %v, want a usage error", err)
+	}
+}
+
+func TestMetaDiff(t *testing.T) {
+	current := &ProfileMeta{Provider: "claude", Model: "opus", Tags: []string{"a"}}
+	want := &ProfileMeta{Provider: "kimi", KeyEnv: "KIMI", Tags: []string{"a", "b"}}
+	got := metaDiff(current, want)
+	expected := []string{"provider claude → kimi", "model opus → (unset)", "key_env (unset) → KIMI", "tags a → a,b"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("metaDiff = %q, want %q", got, expected)
+	}
+	if diff := metaDiff(want, want); len(diff) != 0 {
+		t.Errorf("metaDiff of equal metadata = %q", diff)
+	}
+}
+
+func TestPlanManifest(t *testing.T) {
+	setupManifestHome(t, map[string]ProfileMeta{
+		"work": {Provider: "claude", Model: "opus"},
+		"home": {Provider: "claude"},
+	})
+	// home shares agents, which the manifest doesn't list
+	shared := filepath.Join(getSharedDir(), "agents")
+	if err := os.MkdirAll(shared, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(shared, filepath.Join(getProfilesDir(), "home", "agents")); err != nil {
+		t.Skipf("symlinks not available: %v", err)
+	}
+
+	m, err := loadManifest(writeManifest(t, `
+[profiles.work]
+model = "sonnet"
+shared = ["commands"]
+
+[profiles.home]
+
+[profiles.cheap]
+provider = "kimi"
+dirs = ["/src/cheap"]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := planManifest(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, change := range changes {
+		got = append(got, change.mark+" "+change.desc)
+	}
+	want := []string{
+		"+ create profile cheap (provider: kimi)",
+		"- unlink agents in profile home",
+		"~ update profile work: model opus → sonnet",
+		"+ link commands in profile work",
+		"~ set directory rules: " + filepath.Clean("/src/cheap") + " → cheap",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("plan:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	for _, change := range changes {
+		if err := change.apply(); err != nil {
+			t.Fatalf("%s: %v", change.desc, err)
+		}
+	}
+	if changes, err = planManifest(m); err != nil || len(changes) != 0 {
+		t.Errorf("after applying, plan = %+v, %v", changes, err)
+	}
+}
+
+func TestDumpManifestRoundTrip(t *testing.T) {
+	home := setupManifestHome(t, map[string]ProfileMeta{
+		"work": {
+			Provider:    "claude",
+			Model:       "opus",
+			Description: `Work "main" account`,
+			Tags:        []string{"job", "paid"},
+			Color:       "#00ff00",
+			Owner:       "ops",
+		},
+		"my.kimi": {Provider: "kimi", BaseURL: "https://api.moonshot.cn/anthropic", KeyCommand: "pass show kimi"},
+	})
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Directories = []dirRule{
+		{Path: filepath.Join(home, "code", "work"), Profile: "work"},
+		{Path: filepath.Join(home, "code", "cn"), Profile: "my.kimi"},
+	}
+	if err := saveConfig(config); err != nil {
+		t.Fatal(err)
+	}
+
+	var dump strings.Builder
+	if err := dumpManifest(&dump); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(dump.String(), `dirs = ["~/code/work"]`) {
+		t.Errorf("dump doesn't write dirs below the home directory with ~:\n%s", dump.String())
+	}
+	m, err := loadManifest(writeManifest(t, dump.String()))
+	if err != nil {
+		t.Fatalf("%v in:\n%s", err, dump.String())
+	}
+	changes, err := planManifest(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, change := range changes {
+		t.Errorf("dumped manifest doesn't match: %s %s", change.mark, change.desc)
+	}
+}
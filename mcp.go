@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const claudeJSONFile = ".claude.json"
+
+// claudeJSON is a profile's .claude.json kept as raw values so that fields mcc
+// does not understand (OAuth account, caches, projects) are preserved when
+// it is written back, though keys come out sorted and reindented.
+type claudeJSON struct {
+	path   string
+	fields map[string]json.RawMessage
+}
+
+func loadClaudeJSON(profilePath string) (*claudeJSON, error) {
+	cj := &claudeJSON{
+		path:   filepath.Join(profilePath, claudeJSONFile),
+		fields: make(map[string]json.RawMessage),
+	}
+	data, err := os.ReadFile(cj.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cj, nil
+		}
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return cj, nil
+	}
+	if err := json.Unmarshal(data, &cj.fields); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", cj.path, err)
+	}
+	return cj, nil
+}
+
+func (cj *claudeJSON) mcpServers() (map[string]json.RawMessage, error) {
+	servers := make(map[string]json.RawMessage)
+	raw, ok := cj.fields["mcpServers"]
+	if !ok || string(raw) == "null" {
+		return servers, nil
+	}
+	if err := json.Unmarshal(raw, &servers); err != nil {
+		return nil, fmt.Errorf("invalid mcpServers in %s: %w", cj.path, err)
+	}
+	return servers, nil
+}
+
+func (cj *claudeJSON) setMCPServers(servers map[string]json.RawMessage) error {
+	raw, err := json.Marshal(servers)
+	if err != nil {
+		return err
+	}
+	cj.fields["mcpServers"] = raw
+	return nil
+}
+
+func (cj *claudeJSON) save() error {
+	out, err := json.MarshalIndent(cj.fields, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(cj.path, out, 0600)
+}
+
+// sameJSON reports whether two JSON values are equal regardless of key order
+// and formatting.
+func sameJSON(a, b json.RawMessage) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return bytes.Equal(a, b)
+	}
+	na, _ := json.Marshal(va)
+	nb, _ := json.Marshal(vb)
+	return bytes.Equal(na, nb)
+}
+
+// describeMCPServer gives a one-line summary of a server definition.
+func describeMCPServer(raw json.RawMessage) string {
+	var def struct {
+		Type    string   `json:"type"`
+		Command string   `json:"command"`
+		Args    []string `json:"args"`
+		URL     string   `json:"url"`
+	}
+	if err := json.Unmarshal(raw, &def); err != nil {
+		return "(unreadable definition)"
+	}
+	if def.URL != "" {
+		kind := def.Type
+		if kind == "" {
+			kind = "http"
+		}
+		return fmt.Sprintf("%s %s", kind, def.URL)
+	}
+	return strings.TrimSpace(def.Command + " " + strings.Join(def.Args, " "))
+}
+
+func loadProfileMCPServers(profile string) (map[string]json.RawMessage, error) {
+	if !profileExists(profile) {
+		return nil, profileNotFound(profile)
+	}
+	cj, err := loadClaudeJSON(filepath.Join(getProfilesDir(), profile))
+	if err != nil {
+		return nil, err
+	}
+	return cj.mcpServers()
+}
+
+func listMCPServers(profile string) error {
+	if profile != "" {
+		servers, err := loadProfileMCPServers(profile)
+		if err != nil {
+			return err
+		}
+		if len(servers) == 0 {
+			fmt.Printf("No MCP servers configured in profile: %s\n", profile)
+			return nil
+		}
+		names := make([]string, 0, len(servers))
+		for name := range servers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Printf("MCP servers in profile %s:\n", profile)
+		for _, name := range names {
+			fmt.Printf("  %s  %s\n", name, describeMCPServer(servers[name]))
+		}
+		return nil
+	}
+
+	profiles, err := listProfiles()
+	if err != nil {
+		return err
+	}
+
+	// server name -> profile -> definition
+	byServer := make(map[string]map[string]json.RawMessage)
+	for _, p := range profiles {
+		servers, err := loadProfileMCPServers(p)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  %s: %v\n", p, err)
+			continue
+		}
+		for name, def := range servers {
+			if byServer[name] == nil {
+				byServer[name] = make(map[string]json.RawMessage)
+			}
+			byServer[name][p] = def
+		}
+	}
+
+	if len(byServer) == 0 {
+		fmt.Println("No MCP servers configured in any profile")
+		return nil
+	}
+
+	names := make([]string, 0, len(byServer))
+	for name := range byServer {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Println("MCP servers across profiles:")
+	for _, name := range names {
+		defs := byServer[name]
+		var have, missing []string
+		for _, p := range profiles {
+			if _, ok := defs[p]; ok {
+				have = append(have, p)
+			} else {
+				missing = append(missing, p)
+			}
+		}
+
+		differs := false
+		for _, p := range have[1:] {
+			if !sameJSON(defs[have[0]], defs[p]) {
+				differs = true
+				break
+			}
+		}
+
+		fmt.Printf("  %s  %s\n", name, describeMCPServer(defs[have[0]]))
+		fmt.Printf("      in: %s\n", strings.Join(have, ", "))
+		if len(missing) > 0 {
+			fmt.Printf("      missing: %s\n", strings.Join(missing, ", "))
+		}
+		if differs {
+			fmt.Println("      ⚠️  definitions differ between profiles")
+		}
+	}
+	return nil
+}
+
+// copyMCPServers copies server definitions from one profile into others.
+// Only the mcpServers key of each target .claude.json is modified. When
+// prune is set, servers missing from the source are removed from targets.
+func copyMCPServers(from string, targets []string, only []string, force bool, prune bool) error {
+	source, err := loadProfileMCPServers(from)
+	if err != nil {
+		return err
+	}
+
+	selected := source
+	if len(only) > 0 {
+		selected = make(map[string]json.RawMessage)
+		for _, name := range only {
+			def, ok := source[name]
+			if !ok {
+				return fmt.Errorf("profile '%s' has no MCP server named '%s'", from, name)
+			}
+			selected[name] = def
+		}
+	}
+	if len(selected) == 0 && !prune {
+		return fmt.Errorf("profile '%s' has no MCP servers to copy", from)
+	}
+
+	names := make([]string, 0, len(selected))
+	for name := range selected {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, target := range targets {
+		if target == from {
+			continue
+		}
+		if !profileExists(target) {
+			return profileNotFound(target)
+		}
+
+		cj, err := loadClaudeJSON(filepath.Join(getProfilesDir(), target))
+		if err != nil {
+			return err
+		}
+		servers, err := cj.mcpServers()
+		if err != nil {
+			return err
+		}
+
+		changed := 0
+		for _, name := range names {
+			existing, ok := servers[name]
+			switch {
+			case !ok:
+				fmt.Printf("  %s: + %s\n", target, name)
+			case sameJSON(existing, selected[name]):
+				continue
+			case !force:
+				fmt.Printf("  %s: ~ %s differs, skipped (use --force to overwrite)\n", target, name)
+				continue
+			default:
+				fmt.Printf("  %s: ~ %s\n", target, name)
+			}
+			servers[name] = selected[name]
+			changed++
+		}
+
+		if prune {
+			var extra []string
+			for name := range servers {
+				if _, ok := source[name]; !ok {
+					extra = append(extra, name)
+				}
+			}
+			sort.Strings(extra)
+			for _, name := range extra {
+				fmt.Printf("  %s: - %s\n", target, name)
+				delete(servers, name)
+				changed++
+			}
+		}
+
+		if changed == 0 {
+			fmt.Printf("✓ %s already up to date\n", target)
+			continue
+		}
+		if err := cj.setMCPServers(servers); err != nil {
+			return err
+		}
+		if err := cj.save(); err != nil {
+			return fmt.Errorf("failed to write %s: %w", cj.path, err)
+		}
+		fmt.Printf("✓ Updated %d MCP server(s) in profile: %s\n", changed, target)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+)
+
+// modelPrice is the price of a model in USD per million tokens.
+type modelPrice struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheWrite float64 `json:"cache_write"`
+	CacheRead  float64 `json:"cache_read"`
+}
+
+func (p modelPrice) cost(u tokenUsage) float64 {
+	return (float64(u.InputTokens)*p.Input +
+		float64(u.OutputTokens)*p.Output +
+		float64(u.CacheCreationInputTokens)*p.CacheWrite +
+		float64(u.CacheReadInputTokens)*p.CacheRead) / 1e6
+}
+
+// Built-in list prices, keyed by provider then model pattern. A pattern
+// matches when it is contained in the model name; the longest match wins
+// and "*" matches any model of the provider.
+var defaultPricing = map[string]map[string]modelPrice{
+	"claude": {
+		"opus":           {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.50},
+		"opus-4-5":       {Input: 5, Output: 25, CacheWrite: 6.25, CacheRead: 0.50},
+		"sonnet":         {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30},
+		"haiku":          {Input: 0.80, Output: 4, CacheWrite: 1, CacheRead: 0.08},
+		"haiku-4-5":      {Input: 1, Output: 5, CacheWrite: 1.25, CacheRead: 0.10},
+		"claude-3-haiku": {Input: 0.25, Output: 1.25, CacheWrite: 0.30, CacheRead: 0.03},
+	},
+	"kimi": {
+		"*": {Input: 0.60, Output: 2.50, CacheWrite: 0.60, CacheRead: 0.15},
+	},
+}
+
+func matchPrice(table map[string]modelPrice, model string) (modelPrice, bool) {
+	model = strings.ToLower(model)
+	best := ""
+	for pattern := range table {
+		if pattern != "*" && strings.Contains(model, strings.ToLower(pattern)) && len(pattern) > len(best) {
+			best = pattern
+		}
+	}
+	if best != "" {
+		return table[best], true
+	}
+	price, ok := table["*"]
+	return price, ok
+}
+
+// lookupPrice finds the price for a model, preferring the overrides from
+// config.json over the built-in table.
+func lookupPrice(overrides map[string]map[string]modelPrice, provider, model string) (modelPrice, bool) {
+	if price, ok := matchPrice(overrides[provider], model); ok {
+		return price, true
+	}
+	return matchPrice(defaultPricing[provider], model)
+}
@@ -0,0 +1,54 @@
+package main
+
+import "testing"
+
+func TestMatchPrice(t *testing.T) {
+	claude := defaultPricing["claude"]
+	tests := []struct {
+		table map[string]modelPrice
+		model string
+		want  modelPrice
+		ok    bool
+	}{
+		{claude, "claude-opus-4-1-20250805", claude["opus"], true},
+		{claude, "claude-opus-4-5-20251101", claude["opus-4-5"], true},
+		{claude, "claude-sonnet-4-5", claude["sonnet"], true},
+		{claude, "Claude-Sonnet-4", claude["sonnet"], true},
+		{claude, "claude-haiku-4-5-20251001", claude["haiku-4-5"], true},
+		{claude, "claude-3-haiku-20240307", claude["claude-3-haiku"], true},
+		{claude, "claude-3-5-haiku-20241022", claude["haiku"], true},
+		{claude, "gpt-4o", modelPrice{}, false},
+		{defaultPricing["kimi"], "kimi-k2-turbo", defaultPricing["kimi"]["*"], true},
+		{nil, "claude-opus-4-1", modelPrice{}, false},
+		{map[string]modelPrice{"OPUS": {Input: 1}}, "claude-opus-4", modelPrice{Input: 1}, true},
+	}
+	for _, tt := range tests {
+		got, ok := matchPrice(tt.table, tt.model)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("matchPrice(%q) = %+v, %v; want %+v, %v", tt.model, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLookupPrice(t *testing.T) {
+	overrides := map[string]map[string]modelPrice{
+		"claude": {"opus-4-5": {Input: 1, Output: 2}},
+		"other":  {"*": {Input: 3}},
+	}
+	tests := []struct {
+		provider, model string
+		want            modelPrice
+		ok              bool
+	}{
+		{"claude", "claude-opus-4-5", modelPrice{Input: 1, Output: 2}, true},
+		{"claude", "claude-sonnet-4", defaultPricing["claude"]["sonnet"], true},
+		{"other", "anything", modelPrice{Input: 3}, true},
+		{"unknown", "anything", modelPrice{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := lookupPrice(overrides, tt.provider, tt.model)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("lookupPrice(%s, %s) = %+v, %v; want %+v, %v", tt.provider, tt.model, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// claudeProcesses returns the command lines of running claude processes.
+// claude runs either as its own binary or as a script under node.
+func claudeProcesses() ([]string, error) {
+	out, err := exec.Command("ps", "-Ao", "args=").Output()
+	if err != nil {
+		return nil, err
+	}
+	var processes []string
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		for i := 0; i < len(fields) && i < 2; i++ {
+			if filepath.Base(fields[i]) == "claude" {
+				processes = append(processes, line)
+				break
+			}
+		}
+	}
+	return processes, nil
+}
@@ -0,0 +1,24 @@
+//go:build windows
+
+package main
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// claudeProcesses returns the running claude processes. tasklist doesn't
+// show command lines, so only the image names are returned.
+func claudeProcesses() ([]string, error) {
+	out, err := exec.Command("tasklist", "/FI", "IMAGENAME eq claude.exe", "/FO", "CSV", "/NH").Output()
+	if err != nil {
+		return nil, err
+	}
+	var processes []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(strings.ToLower(strings.TrimSpace(line)), `"claude.exe"`) {
+			processes = append(processes, "claude.exe")
+		}
+	}
+	return processes, nil
+}
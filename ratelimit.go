@@ -0,0 +1,335 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	rateLimitsFileName = "ratelimits.json"
+	// How far back transcripts are searched for limit messages
+	rateLimitLookback = 7 * 24 * time.Hour
+	// Assumed limit window when a message doesn't say when it resets
+	defaultLimitWindow = 5 * time.Hour
+)
+
+// rateLimit is the most recent usage limit a profile ran into.
+type rateLimit struct {
+	LimitedAt time.Time `json:"limited_at"`
+	ResetsAt  time.Time `json:"resets_at,omitempty"`
+	Message   string    `json:"message,omitempty"`
+}
+
+// activeUntil returns when the limit ends, guessing the usual window when
+// the reset time is unknown.
+func (l *rateLimit) activeUntil() time.Time {
+	if !l.ResetsAt.IsZero() {
+		return l.ResetsAt
+	}
+	return l.LimitedAt.Add(defaultLimitWindow)
+}
+
+func (l *rateLimit) active(now time.Time) bool {
+	return now.Before(l.activeUntil())
+}
+
+var (
+	// Only the usage limit wording; an API rate limit error (429) passes in
+	// seconds and doesn't make a profile unavailable
+	limitMessagePattern = regexp.MustCompile(`(?i)(usage limit reached|limit reached|hit your limit)`)
+	apiRateLimitPattern = regexp.MustCompile(`(?i)rate[ _-]?limit`)
+	// Older claude versions: "Claude AI usage limit reached|1760000000"
+	limitEpochPattern = regexp.MustCompile(`\|(\d{9,11})\b`)
+	// Newer ones: "5-hour limit reached ∙ resets 3pm" or "resets Oct 20, 9:30am (Europe/Berlin)"
+	limitResetPattern = regexp.MustCompile(`(?i)resets\s+(?:at\s+)?(?:([A-Z][a-z]{2})\s+(\d{1,2}),?\s+(?:at\s+)?)?(\d{1,2})(?::(\d{2}))?\s*(am|pm)(?:\s*\(([^)]+)\))?`)
+	// API errors claude shows when a login or key stops working
+	authFailurePattern = regexp.MustCompile(`(?i)(invalid api key|please run /login|oauth token (?:has )?expired|authentication_error|api error: 401)`)
+)
+
+// parseLimitMessage recognises a usage limit message and works out when it
+// resets. at is when the message was written.
+func parseLimitMessage(text string, at time.Time) (*rateLimit, bool) {
+	if !limitMessagePattern.MatchString(text) || apiRateLimitPattern.MatchString(text) {
+		return nil, false
+	}
+	limit := &rateLimit{LimitedAt: at, Message: strings.TrimSpace(strings.SplitN(text, "\n", 2)[0])}
+
+	if m := limitEpochPattern.FindStringSubmatch(text); m != nil {
+		if secs, err := strconv.ParseInt(m[1], 10, 64); err == nil {
+			limit.ResetsAt = time.Unix(secs, 0)
+			limit.Message = strings.TrimSpace(text[:strings.Index(text, "|")])
+		}
+		return limit, true
+	}
+
+	m := limitResetPattern.FindStringSubmatch(text)
+	if m == nil {
+		return limit, true
+	}
+	loc := time.Local
+	if m[6] != "" {
+		if l, err := time.LoadLocation(m[6]); err == nil {
+			loc = l
+		}
+	}
+	hour, _ := strconv.Atoi(m[3])
+	minute, _ := strconv.Atoi(m[4])
+	if hour == 12 {
+		hour = 0
+	}
+	if strings.EqualFold(m[5], "pm") {
+		hour += 12
+	}
+
+	base := at.In(loc)
+	year, month, day := base.Date()
+	if m[1] != "" {
+		if t, err := time.Parse("Jan 2", m[1]+" "+m[2]); err == nil {
+			month, day = t.Month(), t.Day()
+		}
+	}
+	reset := time.Date(year, month, day, hour, minute, 0, 0, loc)
+	if m[1] == "" && reset.Before(base) {
+		reset = reset.AddDate(0, 0, 1)
+	} else if m[1] != "" && reset.Before(base) {
+		reset = reset.AddDate(1, 0, 0)
+	}
+	limit.ResetsAt = reset
+	return limit, true
+}
+
+func getRateLimitsPath() string {
+	return filepath.Join(getMccDir(), rateLimitsFileName)
+}
+
+func loadRateLimits() (map[string]*rateLimit, error) {
+	limits := make(map[string]*rateLimit)
+	data, err := os.ReadFile(getRateLimitsPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return limits, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &limits); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", getRateLimitsPath(), err)
+	}
+	return limits, nil
+}
+
+func saveRateLimits(limits map[string]*rateLimit) error {
+	data, err := json.MarshalIndent(limits, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(getRateLimitsPath(), data, 0644)
+}
+
+// recordRateLimit stores a limit for a profile unless a newer one is known.
+func recordRateLimit(profile string, limit *rateLimit) error {
+	unlock, err := lockState()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	limits, err := loadRateLimits()
+	if err != nil {
+		return err
+	}
+	if known, ok := limits[profile]; ok && !known.LimitedAt.Before(limit.LimitedAt) {
+		return nil
+	}
+	limits[profile] = limit
+	return saveRateLimits(limits)
+}
+
+// latestTranscriptLimit finds the most recent limit message in a profile's
+// transcripts written after since.
+func latestTranscriptLimit(profile string, since time.Time) (*rateLimit, error) {
+	var latest *rateLimit
+	err := scanTranscriptsMatching(profile, since, []byte(`"isApiErrorMessage":true`), func(entry transcriptEntry) {
+		if !entry.apiError {
+			return
+		}
+		limit, ok := parseLimitMessage(entry.text(), entry.timestamp)
+		if ok && (latest == nil || limit.LimitedAt.After(latest.LimitedAt)) {
+			latest = limit
+		}
+	})
+	return latest, err
+}
+
+// sessionFailure looks for a usage limit or authentication failure in the
+// profile's transcripts since the given time. It returns a description of
+// the failure, and the limit when it was one.
+func sessionFailure(profile string, since time.Time) (string, *rateLimit, error) {
+	var reason string
+	var limit *rateLimit
+	err := scanTranscriptsMatching(profile, since, []byte(`"isApiErrorMessage":true`), func(entry transcriptEntry) {
+		if !entry.apiError {
+			return
+		}
+		text := entry.text()
+		if l, ok := parseLimitMessage(text, entry.timestamp); ok {
+			reason, limit = "hit its usage limit", l
+		} else if authFailurePattern.MatchString(text) {
+			reason, limit = "failed to authenticate", nil
+		}
+	})
+	return reason, limit, err
+}
+
+// refreshRateLimits merges limit messages found in recent transcripts into
+// the stored limits and returns them. They are only stored with persist,
+// which is for the commands that pick a profile by them; mcc status just
+// shows them.
+func refreshRateLimits(profiles []string, persist bool) (map[string]*rateLimit, error) {
+	limits, err := loadRateLimits()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, profile := range profiles {
+		since := time.Now().Add(-rateLimitLookback)
+		if known, ok := limits[profile]; ok && known.LimitedAt.After(since) {
+			since = known.LimitedAt
+		}
+		limit, err := latestTranscriptLimit(profile, since)
+		if err != nil {
+			return nil, err
+		}
+		if limit == nil {
+			continue
+		}
+		if known, ok := limits[profile]; !ok || limit.LimitedAt.After(known.LimitedAt) {
+			if persist {
+				if err := recordRateLimit(profile, limit); err != nil {
+					return nil, err
+				}
+			}
+			limits[profile] = limit
+		}
+	}
+	return limits, nil
+}
+
+// rateLimitSummary is the short note shown by mcc status for a limited profile.
+func rateLimitSummary(limit *rateLimit) string {
+	until := limit.activeUntil()
+	format := "15:04"
+	if until.Sub(time.Now()) > 24*time.Hour {
+		format = "Jan 2 15:04"
+	}
+	text := "⏳ limited until " + until.Local().Format(format)
+	if limit.ResetsAt.IsZero() {
+		text += " (estimated)"
+	}
+	return text
+}
+
+// orderByPriority sorts profiles by the configured priority list, keeping
+// the rest in their given order after it.
+func orderByPriority(profiles []string, priority []string) []string {
+	rank := make(map[string]int)
+	for i, name := range priority {
+		if _, ok := rank[name]; !ok {
+			rank[name] = i
+		}
+	}
+	ordered := append([]string(nil), profiles...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		ri, iok := rank[ordered[i]]
+		rj, jok := rank[ordered[j]]
+		switch {
+		case iok && jok:
+			return ri < rj
+		case iok != jok:
+			return iok
+		}
+		return false
+	})
+	return ordered
+}
+
+// nextAvailableProfile returns the first profile in priority order that is
+// neither rate limited nor missing its login or API key, ignoring the
+// profiles in skip.
+func nextAvailableProfile(skip map[string]bool) (string, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return "", err
+	}
+	profiles, err := listProfiles()
+	if err != nil {
+		return "", err
+	}
+	limits, err := refreshRateLimits(profiles, true)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	var soonest string
+	for _, profile := range orderByPriority(profiles, config.Priority) {
+		if skip[profile] {
+			continue
+		}
+		if limit, ok := limits[profile]; ok && limit.active(now) {
+			if soonest == "" || limit.activeUntil().Before(limits[soonest].activeUntil()) {
+				soonest = profile
+			}
+			continue
+		}
+		switch inspectLogin(profile).State {
+		case loginNeeded, loginExpired, loginKeyMissing:
+			continue
+		}
+		return profile, nil
+	}
+
+	if soonest != "" {
+		return "", fmt.Errorf("every usable profile is rate limited; '%s' is available again at %s",
+			soonest, limits[soonest].activeUntil().Local().Format("Jan 2 15:04"))
+	}
+	return "", fmt.Errorf("no profile is logged in")
+}
+
+func showPriority() error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	profiles, err := listProfiles()
+	if err != nil {
+		return err
+	}
+	fmt.Println("Launch priority for 'mcc next':")
+	for i, profile := range orderByPriority(profiles, config.Priority) {
+		fmt.Printf("  %d. %s\n", i+1, profile)
+	}
+	return nil
+}
+
+func setPriority(names []string) error {
+	if _, err := resolveProfiles(names); err != nil {
+		return err
+	}
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	config.Priority = names
+	if err := saveConfig(config); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	fmt.Printf("✓ Priority set: %s\n", strings.Join(names, ", "))
+	return nil
+}
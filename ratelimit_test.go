@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseLimitMessage(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	at := time.Date(2026, 10, 18, 10, 0, 0, 0, time.Local)
+	local := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, time.Local)
+	}
+	tests := []struct {
+		name    string
+		text    string
+		at      time.Time
+		resets  time.Time
+		message string
+	}{
+		{"epoch", "Claude AI usage limit reached|1760000000", at, time.Unix(1760000000, 0), "Claude AI usage limit reached"},
+		{"later today", "5-hour limit reached ∙ resets 3pm", at, local(10, 18, 15, 0), "5-hour limit reached ∙ resets 3pm"},
+		{"tomorrow", "5-hour limit reached ∙ resets 9am", at, local(10, 19, 9, 0), "5-hour limit reached ∙ resets 9am"},
+		{"midnight", "limit reached, resets 12am", at, local(10, 19, 0, 0), "limit reached, resets 12am"},
+		{"noon with minutes", "limit reached, resets 12:30pm", at, local(10, 18, 12, 30), "limit reached, resets 12:30pm"},
+		{"date and zone", "Weekly limit reached ∙ resets Oct 20, 9:30am (Europe/Berlin)", at,
+			time.Date(2026, 10, 20, 9, 30, 0, 0, berlin), "Weekly limit reached ∙ resets Oct 20, 9:30am (Europe/Berlin)"},
+		{"next year", "limit reached ∙ resets Jan 2 at 5pm (UTC)", time.Date(2026, 12, 30, 8, 0, 0, 0, time.UTC),
+			time.Date(2027, 1, 2, 17, 0, 0, 0, time.UTC), "limit reached ∙ resets Jan 2 at 5pm (UTC)"},
+		{"no reset time", "You've hit your limit\nUpgrade for more", at, time.Time{}, "You've hit your limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit, ok := parseLimitMessage(tt.text, tt.at)
+			if !ok {
+				t.Fatalf("parseLimitMessage(%q) found no limit", tt.text)
+			}
+			if !limit.ResetsAt.Equal(tt.resets) {
+				t.Errorf("resets at %v, want %v", limit.ResetsAt, tt.resets)
+			}
+			if limit.Message != tt.message || !limit.LimitedAt.Equal(tt.at) {
+				t.Errorf("got %+v, want message %q at %v", limit, tt.message, tt.at)
+			}
+		})
+	}
+
+	// API rate limit errors are not usage limits
+	for _, text := range []string{
+		"", "All done, tests pass", "the limit is 5",
+		"API Error: Rate limit exceeded",
+		`API Error: 429 {"type":"error","error":{"type":"rate_limit_error","message":"Rate limit reached for requests"}}`,
+	} {
+		if limit, ok := parseLimitMessage(text, at); ok {
+			t.Errorf("parseLimitMessage(%q) = %+v, want no limit", text, limit)
+		}
+	}
+}
+
+func TestRateLimitActive(t *testing.T) {
+	at := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	estimated := &rateLimit{LimitedAt: at}
+	if !estimated.active(at.Add(defaultLimitWindow-time.Minute)) || estimated.active(at.Add(defaultLimitWindow)) {
+		t.Errorf("a limit without reset time should last %v", defaultLimitWindow)
+	}
+	known := &rateLimit{LimitedAt: at, ResetsAt: at.Add(time.Hour)}
+	if !known.active(at.Add(59*time.Minute)) || known.active(at.Add(time.Hour)) {
+		t.Errorf("a limit should end at its reset time")
+	}
+}
+
+func TestOrderByPriority(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles []string
+		priority []string
+		want     []string
+	}{
+		{"no priority", []string{"b", "a", "c"}, nil, []string{"b", "a", "c"}},
+		{"full priority", []string{"a", "b", "c"}, []string{"c", "a", "b"}, []string{"c", "a", "b"}},
+		{"rest keep their order", []string{"d", "a", "c", "b"}, []string{"c"}, []string{"c", "d", "a", "b"}},
+		{"unknown names", []string{"a", "b"}, []string{"gone", "b"}, []string{"b", "a"}},
+		{"duplicates use the first rank", []string{"a", "b"}, []string{"b", "a", "b"}, []string{"b", "a"}},
+		{"empty", nil, []string{"a"}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := append([]string(nil), tt.profiles...)
+			got := orderByPriority(tt.profiles, tt.priority)
+			if len(got)+len(tt.want) > 0 && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("orderByPriority(%v, %v) = %v, want %v", tt.profiles, tt.priority, got, tt.want)
+			}
+			if !reflect.DeepEqual(tt.profiles, input) {
+				t.Errorf("orderByPriority changed its input to %v", tt.profiles)
+			}
+		})
+	}
+}
+
+func TestRefreshRateLimits(t *testing.T) {
+	useTempHome(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	resets := now.Add(time.Hour)
+	dir := filepath.Join(getProfileProjectsDir("work"), "-app")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	line := fmt.Sprintf(`{"type":"assistant","timestamp":%q,"isApiErrorMessage":true,"message":{"id":"m1","content":[{"type":"text","text":"Claude AI usage limit reached|%d"}]}}`+"\n",
+		now.Format(time.RFC3339), resets.Unix())
+	if err := os.WriteFile(filepath.Join(dir, "s.jsonl"), []byte(line), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// As mcc status does: shown, not stored
+	limits, err := refreshRateLimits([]string{"work"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limit := limits["work"]; limit == nil || !limit.ResetsAt.Equal(resets) {
+		t.Fatalf("limits = %+v, want work limited until %v", limits, resets)
+	}
+	if _, err := os.Stat(getRateLimitsPath()); !os.IsNotExist(err) {
+		t.Fatalf("refreshing without persist wrote %s", getRateLimitsPath())
+	}
+
+	// As mcc next does
+	if _, err := refreshRateLimits([]string{"work"}, true); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := loadRateLimits()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limit := stored["work"]; limit == nil || !limit.ResetsAt.Equal(resets) {
+		t.Errorf("stored limits = %+v, want work limited until %v", stored, resets)
+	}
+}
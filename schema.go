@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// migration upgrades a decoded file by one schema version.
+type migration func(doc map[string]any)
+
+// Migrations of config.json and .mcc-profile.json. migrations[i] upgrades a
+// file from version i to i+1, so the current version is the length of the
+// list. Files written before versioning have no schema_version (version 0).
+var (
+	configMigrations = []migration{
+		// Only adds the version
+		func(doc map[string]any) {},
+	}
+	profileMetaMigrations = []migration{
+		// An empty provider used to mean claude
+		func(doc map[string]any) {
+			if provider, ok := doc["provider"]; !ok || provider == nil || provider == "" {
+				doc["provider"] = "claude"
+			}
+		},
+	}
+)
+
+var (
+	configSchemaVersion      = len(configMigrations)
+	profileMetaSchemaVersion = len(profileMetaMigrations)
+)
+
+// errNewerSchema marks a state file written by a newer mcc.
+var errNewerSchema = errors.New("was written by a newer mcc")
+
+// corruptFileError is the error for a state file mcc cannot make sense of.
+type corruptFileError struct {
+	path   string
+	detail string
+}
+
+func (e *corruptFileError) Error() string {
+	return fmt.Sprintf("%s is corrupt: %s; fix it, or move it aside with mcc doctor --fix", e.path, e.detail)
+}
+
+// corruptFile describes what is wrong with a state file as precisely as
+// the JSON decoder allows.
+func corruptFile(path string, data []byte, err error) error {
+	detail := err.Error()
+	var syntax *json.SyntaxError
+	if errors.As(err, &syntax) {
+		before := string(data[:syntax.Offset])
+		line := 1 + strings.Count(before, "\n")
+		column := len(before) - strings.LastIndex(before, "\n") - 1
+		detail = fmt.Sprintf("%v at line %d, column %d", syntax, line, column)
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		detail = fmt.Sprintf("%s should be a %s, not a %s", typeErr.Field, typeErr.Type, typeErr.Value)
+	}
+	return withExitCode(exitDataError, &corruptFileError{path: path, detail: detail})
+}
+
+// persistUpgrades is set for commands that change mcc's files. Only they
+// write upgraded state files back; other commands upgrade them in memory,
+// so that reading never changes anything on disk.
+var persistUpgrades bool
+
+// loadVersioned reads a JSON state file into v, upgrading it first when it
+// has an older schema version. With persistUpgrades the file is rewritten
+// after an upgrade and the original kept next to it as
+// <name>.v<version>.bak.
+func loadVersioned(path string, migrations []migration, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return corruptFile(path, data, err)
+	}
+	if doc == nil {
+		return corruptFile(path, data, errors.New("expected a JSON object"))
+	}
+
+	version := 0
+	if raw, ok := doc["schema_version"]; ok {
+		number, ok := raw.(float64)
+		if !ok || number < 0 || number != float64(int(number)) {
+			return corruptFile(path, data, fmt.Errorf("invalid schema_version %v", raw))
+		}
+		version = int(number)
+	}
+	if version > len(migrations) {
+		return withExitCode(exitDataError, fmt.Errorf("%s %w (schema version %d, this one knows up to %d); upgrade mcc", path, errNewerSchema, version, len(migrations)))
+	}
+
+	if version == len(migrations) {
+		if err := json.Unmarshal(data, v); err != nil {
+			return corruptFile(path, data, err)
+		}
+		return nil
+	}
+
+	for _, migrate := range migrations[version:] {
+		migrate(doc)
+	}
+	doc["schema_version"] = len(migrations)
+	upgraded, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Check the result before replacing the file with it
+	if err := json.Unmarshal(upgraded, v); err != nil {
+		return corruptFile(path, data, err)
+	}
+	if !persistUpgrades {
+		return nil
+	}
+	unlock, err := lockState()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	backup, err := backupAndWrite(path, data, upgraded, version)
+	if err != nil {
+		return fmt.Errorf("failed to upgrade %s: %w", path, err)
+	}
+	notef("Upgraded %s to schema version %d (previous version kept as %s)\n", shortenHome(path), len(migrations), filepath.Base(backup))
+	return nil
+}
+
+// backupAndWrite keeps the old contents of an upgraded file and writes the
+// new ones with the same permissions. It returns the backup's path.
+func backupAndWrite(path string, old, upgraded []byte, version int) (string, error) {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	backup := fmt.Sprintf("%s.v%d.bak", path, version)
+	if !fileExists(backup) {
+		if err := writeFileAtomic(backup, old, mode); err != nil {
+			return "", err
+		}
+	}
+	return backup, writeFileAtomic(path, upgraded, mode)
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func writeStateFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), profileMetaFile)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadVersionedMigrations(t *testing.T) {
+	useTempHome(t)
+	tests := []struct {
+		name     string
+		content  string
+		provider string
+		upgraded bool
+	}{
+		{"before versioning", `{"api_key": "k"}`, "claude", true},
+		{"empty provider", `{"provider": ""}`, "claude", true},
+		{"null provider", `{"provider": null}`, "claude", true},
+		{"kept provider", `{"provider": "kimi"}`, "kimi", true},
+		{"version 0", `{"schema_version": 0, "provider": "kimi"}`, "kimi", true},
+		{"current", `{"schema_version": 1, "provider": "kimi"}`, "kimi", false},
+	}
+	for _, persist := range []bool{false, true} {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				persistUpgrades = persist
+				t.Cleanup(func() { persistUpgrades = false })
+				path := writeStateFile(t, tt.content)
+
+				var meta ProfileMeta
+				if err := loadVersioned(path, profileMetaMigrations, &meta); err != nil {
+					t.Fatal(err)
+				}
+				if meta.Provider != tt.provider {
+					t.Errorf("provider = %q, want %q", meta.Provider, tt.provider)
+				}
+
+				data, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				_, backupErr := os.Stat(path + ".v0.bak")
+				if !persist || !tt.upgraded {
+					if string(data) != tt.content || backupErr == nil {
+						t.Errorf("file changed on read: %s", data)
+					}
+					return
+				}
+				var doc map[string]any
+				if err := json.Unmarshal(data, &doc); err != nil {
+					t.Fatal(err)
+				}
+				if doc["schema_version"] != float64(profileMetaSchemaVersion) || doc["provider"] != tt.provider {
+					t.Errorf("upgraded file = %s", data)
+				}
+				backup, err := os.ReadFile(path + ".v0.bak")
+				if err != nil || string(backup) != tt.content {
+					t.Errorf("backup = %q, %v; want the original", backup, err)
+				}
+				if info, err := os.Stat(path); err == nil && runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+					t.Errorf("upgrade changed permissions to %v", info.Mode().Perm())
+				}
+			})
+		}
+	}
+}
+
+func TestLoadVersionedErrors(t *testing.T) {
+	useTempHome(t)
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"syntax", "{\n  \"provider\": \"kimi\",\n}", "line 3, column 1"},
+		{"not an object", `["kimi"]`, "is corrupt"},
+		{"null", `null`, "expected a JSON object"},
+		{"wrong type", `{"schema_version": 1, "provider": 7}`, "provider should be a string"},
+		{"bad version", `{"schema_version": "1"}`, "invalid schema_version 1"},
+		{"negative version", `{"schema_version": -1}`, "invalid schema_version -1"},
+		{"fractional version", `{"schema_version": 0.5}`, "invalid schema_version 0.5"},
+		{"newer version", `{"schema_version": 99}`, "written by a newer mcc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var meta ProfileMeta
+			err := loadVersioned(writeStateFile(t, tt.content), profileMetaMigrations, &meta)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error = %v, want %q", err, tt.want)
+			}
+			if exitCodeOf(err) != exitDataError {
+				t.Errorf("exit code = %d, want %d", exitCodeOf(err), exitDataError)
+			}
+		})
+	}
+
+	var meta ProfileMeta
+	err := loadVersioned(writeStateFile(t, `{"schema_version": 99}`), profileMetaMigrations, &meta)
+	if !errors.Is(err, errNewerSchema) {
+		t.Errorf("error %v doesn't wrap errNewerSchema", err)
+	}
+}
+
+func TestLoadProfileMetaProvider(t *testing.T) {
+	useTempHome(t)
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"no file", ""},
+		{"current without provider", `{"schema_version": 1, "model": "opus"}`},
+		{"current with empty provider", `{"schema_version": 1, "provider": ""}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profilePath := t.TempDir()
+			if tt.content != "" {
+				if err := os.WriteFile(filepath.Join(profilePath, profileMetaFile), []byte(tt.content), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			meta, err := loadProfileMeta(profilePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meta.Provider != "claude" {
+				t.Errorf("provider = %q, want claude", meta.Provider)
+			}
+			env, err := getProviderEnv(meta)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, v := range env {
+				if strings.HasPrefix(v, "ANTHROPIC_API_KEY=") {
+					t.Errorf("a claude profile without a key gets %s", v)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+)
+
+// secretPolicy says what to do with a file whose contents look like they
+// hold a secret.
+type secretPolicy string
+
+const (
+	secretsRefuse secretPolicy = "refuse" // leave the file out (default)
+	secretsRedact secretPolicy = "redact" // copy it with the secrets masked
+	secretsAllow  secretPolicy = "allow"  // copy it unchanged without scanning
+)
+
+const redactedSecret = "[REDACTED]"
+
+func parseSecretPolicy(value string) (secretPolicy, error) {
+	switch secretPolicy(value) {
+	case "":
+		return secretsRefuse, nil
+	case secretsRefuse, secretsRedact, secretsAllow:
+		return secretPolicy(value), nil
+	}
+	return "", usageError("invalid --secrets value '%s' (use refuse, redact or allow)", value)
+}
+
+type secretPattern struct {
+	kind  string
+	re    *regexp.Regexp
+	group int // submatch holding the secret itself
+}
+
+// Checked in order; a later match overlapping an earlier one is ignored.
+var secretPatterns = []secretPattern{
+	{"Anthropic API key", regexp.MustCompile(`sk-ant-[A-Za-z0-9_\-]{20,}`), 0},
+	{"OpenAI API key", regexp.MustCompile(`sk-proj-[A-Za-z0-9_\-]{20,}`), 0},
+	{"Kimi/OpenAI API key", regexp.MustCompile(`sk-[A-Za-z0-9]{32,}`), 0},
+	{"token field", regexp.MustCompile(`(?i)"[a-z_]*(?:api_?key|auth_?token|access_?token|refresh_?token|oauth_?token|secret)"\s*:\s*"([^"]{8,})"`), 1},
+	{"key variable", regexp.MustCompile(`(?i)\b(?:ANTHROPIC|OPENAI|MOONSHOT|KIMI)_(?:API_KEY|AUTH_TOKEN)=["']?([^\s"']{8,})`), 1},
+}
+
+type secretSpan struct {
+	start, end int
+	kind       string
+}
+
+// secretFinding is a possible secret found in a file.
+type secretFinding struct {
+	path string
+	line int
+	kind string
+}
+
+func findSecrets(line []byte) []secretSpan {
+	var spans []secretSpan
+	for _, pattern := range secretPatterns {
+		for _, m := range pattern.re.FindAllSubmatchIndex(line, -1) {
+			start, end := m[2*pattern.group], m[2*pattern.group+1]
+			if start < 0 || string(line[start:end]) == redactedSecret {
+				continue
+			}
+			overlaps := false
+			for _, s := range spans {
+				if start < s.end && s.start < end {
+					overlaps = true
+					break
+				}
+			}
+			if !overlaps {
+				spans = append(spans, secretSpan{start: start, end: end, kind: pattern.kind})
+			}
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	return spans
+}
+
+// forEachLine calls fn for every line of r, newline included, without
+// holding more than one line in memory.
+func forEachLine(r io.Reader, fn func(line []byte) error) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if fnErr := fn(line); fnErr != nil {
+				return fnErr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// scanFileSecrets reports the possible secrets in a file. rel is the path
+// used in the findings.
+func scanFileSecrets(path, rel string) ([]secretFinding, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var findings []secretFinding
+	lineNo := 0
+	err = forEachLine(f, func(line []byte) error {
+		lineNo++
+		for _, span := range findSecrets(line) {
+			findings = append(findings, secretFinding{path: rel, line: lineNo, kind: span.kind})
+		}
+		return nil
+	})
+	return findings, err
+}
+
+func redactLine(line []byte) []byte {
+	spans := findSecrets(line)
+	if len(spans) == 0 {
+		return line
+	}
+	var out bytes.Buffer
+	last := 0
+	for _, span := range spans {
+		out.Write(line[last:span.start])
+		out.WriteString(redactedSecret)
+		last = span.end
+	}
+	out.Write(line[last:])
+	return out.Bytes()
+}
+
+// redactFile copies src to dst with every secret masked, keeping mode and
+// mtime like copyFile.
+func redactFile(src, dst string, info fs.FileInfo) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := createAtomic(dst, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(out)
+	err = forEachLine(in, func(line []byte) error {
+		_, err := w.Write(redactLine(line))
+		return err
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		out.abort()
+		return err
+	}
+	if err := out.commit(); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// secretCheck returns the copy action for a regular file under policy,
+// recording what it found.
+func secretCheck(path, rel string, policy secretPolicy, findings *[]secretFinding) copyAction {
+	if policy == secretsAllow {
+		return copyInclude
+	}
+	found, err := scanFileSecrets(path, rel)
+	if err != nil {
+		// Let the copy itself report the read error
+		return copyInclude
+	}
+	if len(found) == 0 {
+		return copyInclude
+	}
+	*findings = append(*findings, found...)
+	if policy == secretsRedact {
+		return copyRedacted
+	}
+	return copyExclude
+}
+
+// reportSecrets prints the possible secrets found while copying and what
+// was done about them.
+func reportSecrets(findings []secretFinding, policy secretPolicy) {
+	if len(findings) == 0 {
+		return
+	}
+	if policy == secretsRedact {
+		fmt.Fprintln(os.Stderr, "⚠️  Possible secrets found, copied with them redacted:")
+	} else {
+		fmt.Fprintln(os.Stderr, "⚠️  Possible secrets found, these files were not copied:")
+	}
+	// One line per file, naming the first secret found in it
+	for i := 0; i < len(findings); {
+		f := findings[i]
+		n := 1
+		for i+n < len(findings) && findings[i+n].path == f.path {
+			n++
+		}
+		more := ""
+		if n > 1 {
+			more = fmt.Sprintf(" (and %d more)", n-1)
+		}
+		fmt.Fprintf(os.Stderr, "   %s:%d  %s%s\n", f.path, f.line, f.kind, more)
+		i += n
+	}
+	if policy == secretsRefuse {
+		fmt.Fprintln(os.Stderr, "   Use --secrets redact to copy them with secrets masked, or --secrets allow to copy them as-is.")
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Built from parts so that the file itself doesn't look like it holds keys
+var (
+	testAnthropicKey = "sk-" + "ant-api03-" + strings.Repeat("a1B2", 8)
+	testOpenAIKey    = "sk-" + "proj-" + strings.Repeat("x9Y8", 8)
+	testKimiKey      = "sk-" + strings.Repeat("k7M6", 10)
+)
+
+func TestFindSecrets(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		kinds []string
+	}{
+		{"nothing", `{"model": "opus", "theme": "dark"}`, nil},
+		{"short sk- word", "task-sk-short", nil},
+		{"anthropic key", "key: " + testAnthropicKey, []string{"Anthropic API key"}},
+		{"openai key", testOpenAIKey, []string{"OpenAI API key"}},
+		{"kimi key", "export X=" + testKimiKey, []string{"Kimi/OpenAI API key"}},
+		{"token field", `"oauthToken": "abcdefgh12345"`, []string{"token field"}},
+		{"short token field", `"apiKey": "short"`, nil},
+		{"key variable", "ANTHROPIC_AUTH_TOKEN='abcdefgh12345'", []string{"key variable"}},
+		{"lowercase variable", "moonshot_api_key=abcdefgh12345", []string{"key variable"}},
+		// The field pattern overlaps the key found first
+		{"key in a token field", `"primaryApiKey": "` + testAnthropicKey + `"`, []string{"Anthropic API key"}},
+		{"two keys", testOpenAIKey + " " + testAnthropicKey, []string{"OpenAI API key", "Anthropic API key"}},
+		{"already redacted", `"apiKey": "[REDACTED]"`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var kinds []string
+			for _, span := range findSecrets([]byte(tt.line)) {
+				kinds = append(kinds, span.kind)
+			}
+			if !reflect.DeepEqual(kinds, tt.kinds) {
+				t.Errorf("findSecrets(%q) found %q, want %q", tt.line, kinds, tt.kinds)
+			}
+		})
+	}
+}
+
+func TestRedactLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"nothing here\n", "nothing here\n"},
+		{"key " + testAnthropicKey + " end\n", "key [REDACTED] end\n"},
+		{`{"apiKey": "abcdefgh12345", "model": "opus"}`, `{"apiKey": "[REDACTED]", "model": "opus"}`},
+		{"KIMI_API_KEY=\"abcdefgh12345\"\n", "KIMI_API_KEY=\"[REDACTED]\"\n"},
+		{testOpenAIKey + "," + testKimiKey, "[REDACTED],[REDACTED]"},
+		{`"apiKey": "[REDACTED]"`, `"apiKey": "[REDACTED]"`},
+	}
+	for _, tt := range tests {
+		got := string(redactLine([]byte(tt.line)))
+		if got != tt.want {
+			t.Errorf("redactLine(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+		if again := string(redactLine([]byte(got))); again != got {
+			t.Errorf("redactLine isn't stable: %q became %q", got, again)
+		}
+	}
+}
+
+func TestSyncSettingsRefusesSecrets(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	write := func(dir, rel, content string) {
+		t.Helper()
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(dir, rel string) string {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err != nil {
+			return "(missing)"
+		}
+		return string(data)
+	}
+	write(src, "settings.json", `{"model": "opus"}`)
+	write(src, "projects/-app/s.jsonl", "{\"text\": \"fine\"}\n")
+	if _, _, err := syncSettings(src, dst, secretsRefuse); err != nil {
+		t.Fatal(err)
+	}
+
+	// The transcript now mentions a key
+	transcript := "{\"text\": \"fine\"}\n{\"text\": \"" + testAnthropicKey + "\"}\n"
+	write(src, "projects/-app/s.jsonl", transcript)
+	if _, _, err := syncSettings(src, dst, secretsRefuse); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(dst, "projects/-app/s.jsonl"); got != "{\"text\": \"fine\"}\n" {
+		t.Errorf("refused transcript changed the older copy: %q", got)
+	}
+	if got := read(dst, "settings.json"); got != `{"model": "opus"}` {
+		t.Errorf("settings.json = %q", got)
+	}
+
+	// The target's own files are never removed, whatever the source holds
+	write(dst, "CLAUDE.md", "target notes")
+	write(src, "CLAUDE.md", "notes "+testAnthropicKey)
+	if _, _, err := syncSettings(src, dst, secretsRefuse); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(dst, "CLAUDE.md"); got != "target notes" {
+		t.Errorf("target's CLAUDE.md = %q", got)
+	}
+
+	if _, _, err := syncSettings(src, dst, secretsRedact); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := read(dst, "projects/-app/s.jsonl"), strings.Replace(transcript, testAnthropicKey, redactedSecret, 1); got != want {
+		t.Errorf("redacted transcript = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,470 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Longest first prompt shown in the session list.
+const sessionPromptWidth = 60
+
+// sessionInfo describes one claude conversation in a profile.
+type sessionInfo struct {
+	ID          string
+	Profile     string
+	Project     string
+	Started     time.Time
+	Updated     time.Time
+	FirstPrompt string
+	Messages    int
+	// Transcript files holding the session
+	Paths []string
+
+	text strings.Builder
+}
+
+// isPromptText reports whether a user message is something the user typed
+// rather than a slash command or output claude records as a user message.
+func isPromptText(text string) bool {
+	text = strings.TrimSpace(text)
+	return text != "" && !strings.HasPrefix(text, "<") && !strings.HasPrefix(text, "Caveat:")
+}
+
+// collectSessions gathers the sessions of the given profiles. When
+// withText is set, the text of every message is kept for searching.
+func collectSessions(profiles []string, withText bool) ([]*sessionInfo, error) {
+	var sessions []*sessionInfo
+	for _, profile := range profiles {
+		byID := make(map[string]*sessionInfo)
+		err := scanTranscripts(profile, time.Time{}, func(entry transcriptEntry) {
+			session, ok := byID[entry.sessionID]
+			if !ok {
+				session = &sessionInfo{ID: entry.sessionID, Profile: profile, Project: entry.project}
+				byID[entry.sessionID] = session
+			}
+			session.Messages++
+			if !containsString(session.Paths, entry.path) {
+				session.Paths = append(session.Paths, entry.path)
+			}
+			if session.Started.IsZero() || entry.timestamp.Before(session.Started) {
+				session.Started = entry.timestamp
+			}
+			if entry.timestamp.After(session.Updated) {
+				session.Updated = entry.timestamp
+			}
+
+			if entry.role != "user" || entry.meta {
+				if withText && entry.role == "assistant" {
+					session.text.WriteString(entry.text())
+					session.text.WriteByte('\n')
+				}
+				return
+			}
+			text := entry.text()
+			if session.FirstPrompt == "" && isPromptText(text) {
+				session.FirstPrompt = text
+			}
+			if withText {
+				session.text.WriteString(text)
+				session.text.WriteByte('\n')
+			}
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read transcripts of profile '%s': %w", profile, err)
+		}
+		for _, session := range byID {
+			sessions = append(sessions, session)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Updated.After(sessions[j].Updated) })
+	return sessions, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// sessionFilter holds the options of mcc sessions.
+type sessionFilter struct {
+	profile string
+	project string
+	grep    string
+	limit   int
+}
+
+func filterSessions(sessions []*sessionInfo, filter sessionFilter) []*sessionInfo {
+	project := filter.project
+	if project != "" {
+		if abs, err := filepath.Abs(project); err == nil {
+			project = abs
+		}
+	}
+	grep := strings.ToLower(filter.grep)
+
+	var matched []*sessionInfo
+	for _, session := range sessions {
+		if project != "" && session.Project != project && !strings.HasPrefix(session.Project, project+string(filepath.Separator)) {
+			continue
+		}
+		if grep != "" && !strings.Contains(strings.ToLower(session.text.String()), grep) {
+			continue
+		}
+		matched = append(matched, session)
+	}
+	return matched
+}
+
+// shortenPrompt turns a prompt into a single line of at most width runes.
+func shortenPrompt(text string, width int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) > width {
+		return string(runes[:width-1]) + "…"
+	}
+	return text
+}
+
+// shortenHome replaces the home directory at the start of a path with ~.
+func shortenHome(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return path
+	}
+	if path == home {
+		return "~"
+	}
+	if strings.HasPrefix(path, home+string(filepath.Separator)) {
+		return "~" + path[len(home):]
+	}
+	return path
+}
+
+func showSessions(filter sessionFilter) error {
+	var profiles []string
+	var err error
+	if filter.profile != "" {
+		profiles, err = resolveProfiles([]string{filter.profile})
+	} else {
+		profiles, err = listProfiles()
+	}
+	if err != nil {
+		return err
+	}
+
+	sessions, err := collectSessions(profiles, filter.grep != "")
+	if err != nil {
+		return err
+	}
+	sessions = filterSessions(sessions, filter)
+	if len(sessions) == 0 {
+		fmt.Println("No sessions found")
+		return nil
+	}
+
+	shown := sessions
+	if filter.limit > 0 && len(shown) > filter.limit {
+		shown = shown[:filter.limit]
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SESSION\tPROFILE\tLAST ACTIVE\tPROJECT\tFIRST PROMPT")
+	for _, session := range shown {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			session.ID, session.Profile,
+			session.Updated.Local().Format("2006-01-02 15:04"),
+			shortenHome(session.Project),
+			shortenPrompt(session.FirstPrompt, sessionPromptWidth))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if len(shown) < len(sessions) {
+		fmt.Printf("\n(%d more, use --limit 0 to show all)\n", len(sessions)-len(shown))
+	}
+	return nil
+}
+
+// findSession looks up a session by its ID or a unique prefix of it, in
+// the given profile or, when profile is empty, in all of them.
+func findSession(id, profile string) (*sessionInfo, error) {
+	var profiles []string
+	var err error
+	if profile != "" {
+		profiles, err = resolveProfiles([]string{profile})
+	} else {
+		profiles, err = listProfiles()
+	}
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := collectSessions(profiles, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// The same session can be in several profiles after mcc session copy
+	ids := make(map[string]bool)
+	for _, session := range sessions {
+		if session.ID == id {
+			ids = map[string]bool{id: true}
+			break
+		}
+		if strings.HasPrefix(session.ID, id) {
+			ids[session.ID] = true
+		}
+	}
+	if len(ids) > 1 {
+		return nil, fmt.Errorf("session ID '%s' is ambiguous (%d matches), use more characters", id, len(ids))
+	}
+
+	var matches []*sessionInfo
+	var in []string
+	for _, session := range sessions {
+		if ids[session.ID] {
+			matches = append(matches, session)
+			in = append(in, session.Profile)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		if profile != "" {
+			return nil, fmt.Errorf("no session '%s' found in profile '%s'", id, profile)
+		}
+		return nil, fmt.Errorf("no session '%s' found in any profile", id)
+	case 1:
+		return matches[0], nil
+	}
+	return nil, fmt.Errorf("session %s is in several profiles (%s), pick one with --profile", matches[0].ID, strings.Join(in, ", "))
+}
+
+// resumeSession launches claude with --resume in the profile and project
+// directory the session belongs to.
+func resumeSession(id, profile string, supervise bool) error {
+	session, err := findSession(id, profile)
+	if err != nil {
+		return err
+	}
+	if session.Project != "" {
+		if err := os.Chdir(session.Project); err != nil {
+			return fmt.Errorf("cannot enter the session's project directory: %w", err)
+		}
+	}
+	notef("Resuming session %s in profile '%s' (%s)\n", session.ID, session.Profile, shortenHome(session.Project))
+	return runProfile(session.Profile, supervise, []string{"--resume", session.ID})
+}
+
+// Per-session state claude keeps next to the transcripts, relative to the
+// profile directory. %s is the session ID.
+var sessionStatePatterns = []string{
+	"todos/%s-*.json",
+	"file-history/%s",
+	"session-env/%s",
+}
+
+// How recently an open session's transcript has usually been written to.
+const sessionActiveWindow = 10 * time.Minute
+
+var slugPattern = regexp.MustCompile(`"slug":"([A-Za-z0-9_-]+)"`)
+
+// sessionFiles returns the files and directories that make up a session,
+// relative to its profile directory: the transcripts and the directory
+// holding subagent transcripts and tool results, todos, file history, and
+// the plans the session wrote.
+func sessionFiles(session *sessionInfo) ([]string, error) {
+	profileDir := filepath.Join(getProfilesDir(), session.Profile)
+	var files []string
+	add := func(path string) {
+		rel, err := filepath.Rel(profileDir, path)
+		if err == nil && !containsString(files, rel) {
+			files = append(files, rel)
+		}
+	}
+
+	slugs := make(map[string]bool)
+	for _, path := range session.Paths {
+		add(path)
+		if dir := filepath.Join(filepath.Dir(path), session.ID); isDir(dir) {
+			add(dir)
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		err = forEachLine(f, func(line []byte) error {
+			if m := slugPattern.FindSubmatch(line); m != nil {
+				slugs[string(m[1])] = true
+			}
+			return nil
+		})
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, pattern := range sessionStatePatterns {
+		matches, err := filepath.Glob(filepath.Join(profileDir, fmt.Sprintf(pattern, session.ID)))
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			add(match)
+		}
+	}
+	for slug := range slugs {
+		if plan := filepath.Join(profileDir, "plans", slug+".md"); fileExists(plan) {
+			add(plan)
+		}
+	}
+
+	// Subagent transcripts are already part of the session directory
+	var result []string
+	for _, rel := range files {
+		inside := false
+		for _, other := range files {
+			if strings.HasPrefix(rel, other+string(filepath.Separator)) {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			result = append(result, rel)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// sessionOpen reports whether a claude process looks to have the session
+// open: one was started with the session ID, or one is running and the
+// transcript was written to recently.
+func sessionOpen(session *sessionInfo) (bool, error) {
+	processes, err := claudeProcesses()
+	if err != nil {
+		return false, err
+	}
+	if len(processes) == 0 {
+		return false, nil
+	}
+	for _, args := range processes {
+		if strings.Contains(args, session.ID) {
+			return true, nil
+		}
+	}
+	for _, path := range session.Paths {
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) < sessionActiveWindow {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// samePlace reports whether two paths end up in the same directory, which
+// happens when the directory is shared between profiles with mcc link.
+func samePlace(a, b string) bool {
+	ra, errA := filepath.EvalSymlinks(filepath.Dir(a))
+	rb, errB := filepath.EvalSymlinks(filepath.Dir(b))
+	return errA == nil && errB == nil && ra == rb
+}
+
+// transferSession copies a session's files into the same places in the
+// target profile so that claude --resume finds it there, removing them from
+// the session's profile afterwards when move is set. It never overwrites
+// files already in the target and returns how many entries it transferred.
+func transferSession(session *sessionInfo, target string, move bool) (int, error) {
+	if target == session.Profile {
+		return 0, fmt.Errorf("session %s is already in profile '%s'", session.ID, target)
+	}
+	if !profileExists(target) {
+		return 0, profileNotFound(target)
+	}
+	files, err := sessionFiles(session)
+	if err != nil {
+		return 0, fmt.Errorf("failed to collect the session's files: %w", err)
+	}
+
+	srcDir := filepath.Join(getProfilesDir(), session.Profile)
+	dstDir := filepath.Join(getProfilesDir(), target)
+	var pending []string
+	for _, rel := range files {
+		src, dst := filepath.Join(srcDir, rel), filepath.Join(dstDir, rel)
+		if samePlace(src, dst) {
+			// Shared between the profiles, nothing to do
+			continue
+		}
+		if fileExists(dst) {
+			return 0, fmt.Errorf("profile '%s' already has %s", target, rel)
+		}
+		pending = append(pending, rel)
+	}
+
+	for _, rel := range pending {
+		dst := filepath.Join(dstDir, rel)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return 0, err
+		}
+		if err := copyTree(filepath.Join(srcDir, rel), dst, nil).err(); err != nil {
+			return 0, fmt.Errorf("failed to copy %s: %w", rel, err)
+		}
+	}
+	if move {
+		for _, rel := range pending {
+			if err := os.RemoveAll(filepath.Join(srcDir, rel)); err != nil {
+				return len(pending), fmt.Errorf("copied, but failed to remove %s from profile '%s': %w", rel, session.Profile, err)
+			}
+		}
+	}
+	return len(pending), nil
+}
+
+// moveSession is mcc session move|copy.
+func moveSession(id, from, target string, move, force bool) error {
+	session, err := findSession(id, from)
+	if err != nil {
+		return err
+	}
+	if !force {
+		open, err := sessionOpen(session)
+		if err != nil {
+			return fmt.Errorf("cannot tell whether the session is open: %v", err)
+		}
+		if open {
+			return fmt.Errorf("session %s looks to be open in a running claude; close it first (or pass --force)", session.ID)
+		}
+	}
+
+	n, err := transferSession(session, target, move)
+	if err != nil {
+		return err
+	}
+	verb := "Copied"
+	if move {
+		verb = "Moved"
+	}
+	fmt.Printf("✓ %s session %s from '%s' to '%s' (%d item(s))\n", verb, session.ID, session.Profile, target, n)
+	fmt.Printf("  Resume it with: mcc sessions resume %s --profile %s\n", session.ID, target)
+	return nil
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Lines around the block mcc init writes to a shell rc file.
+const (
+	shellBlockStart = "# >>> mcc >>>"
+	shellBlockEnd   = "# <<< mcc <<<"
+)
+
+var supportedShells = []string{"zsh", "bash", "fish"}
+
+// detectShell returns the user's login shell if mcc supports it.
+func detectShell() (string, error) {
+	shell := filepath.Base(os.Getenv("SHELL"))
+	if containsString(supportedShells, shell) {
+		return shell, nil
+	}
+	if shell == "" || shell == "." {
+		return "", fmt.Errorf("cannot tell which shell you use, pass --shell %s", strings.Join(supportedShells, "|"))
+	}
+	return "", fmt.Errorf("unsupported shell '%s', pass --shell %s", shell, strings.Join(supportedShells, "|"))
+}
+
+// shellRCFile returns the rc file mcc init writes to for a shell.
+func shellRCFile(shell string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	switch shell {
+	case "zsh":
+		if dir := os.Getenv("ZDOTDIR"); dir != "" {
+			return filepath.Join(dir, ".zshrc"), nil
+		}
+		return filepath.Join(home, ".zshrc"), nil
+	case "bash":
+		// Terminals on macOS start login shells, which don't read .bashrc
+		if runtime.GOOS == "darwin" {
+			return filepath.Join(home, ".bash_profile"), nil
+		}
+		return filepath.Join(home, ".bashrc"), nil
+	case "fish":
+		config := os.Getenv("XDG_CONFIG_HOME")
+		if config == "" {
+			config = filepath.Join(home, ".config")
+		}
+		return filepath.Join(config, "fish", "config.fish"), nil
+	}
+	return "", usageError("unsupported shell '%s', use one of: %s", shell, strings.Join(supportedShells, ", "))
+}
+
+// mccBinDir returns the directory of the running mcc when it isn't on PATH
+// yet, so that mcc init can add it.
+func mccBinDir() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	dir := filepath.Dir(exe)
+	for _, entry := range filepath.SplitList(os.Getenv("PATH")) {
+		if entry == dir {
+			return ""
+		}
+	}
+	// go run builds into a temporary directory that won't be there later
+	if strings.HasPrefix(dir, os.TempDir()) {
+		return ""
+	}
+	return dir
+}
+
+// shellQuote quotes s for sh, bash and zsh, which take everything between
+// single quotes literally.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fishQuote quotes s for fish, where single quotes only know \' and \\.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// shellBlock returns the marked block mcc init writes for a shell.
+func shellBlock(shell string) string {
+	lines := []string{shellBlockStart, "# Written by 'mcc init', remove with 'mcc uninstall'"}
+	binDir := mccBinDir()
+	if shell == "fish" {
+		if home := customHome(); home != "" {
+			lines = append(lines, "set -gx MCC_HOME "+fishQuote(home))
+		}
+		lines = append(lines, "set -gx CLAUDE_CONFIG_DIR "+fishQuote(getCurrentLink()))
+		if binDir != "" {
+			lines = append(lines, "fish_add_path "+fishQuote(binDir))
+		}
+	} else {
+		if home := customHome(); home != "" {
+			lines = append(lines, "export MCC_HOME="+shellQuote(home))
+		}
+		lines = append(lines, "export CLAUDE_CONFIG_DIR="+shellQuote(getCurrentLink()))
+		if binDir != "" {
+			lines = append(lines, "export PATH="+shellQuote(binDir)+`:"$PATH"`)
+		}
+	}
+	lines = append(lines, shellBlockEnd)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// shellBlockBounds returns where mcc's block starts and ends in an rc
+// file's contents, its final newline included.
+func shellBlockBounds(content string) (start, end int, found bool) {
+	start = strings.Index(content, shellBlockStart)
+	if start < 0 {
+		return 0, 0, false
+	}
+	end = strings.Index(content[start:], shellBlockEnd)
+	if end < 0 {
+		return 0, 0, false
+	}
+	end += start + len(shellBlockEnd)
+	if end < len(content) && content[end] == '\n' {
+		end++
+	}
+	return start, end, true
+}
+
+// splitShellBlock returns an rc file's contents without mcc's block, and
+// whether the block was there.
+func splitShellBlock(content string) (string, bool) {
+	start, end, found := shellBlockBounds(content)
+	if !found {
+		return content, false
+	}
+	before := content[:start]
+	if content[end:] == "" && strings.HasSuffix(before, "\n\n") {
+		// Drop the blank line mcc init put before the block
+		before = before[:len(before)-1]
+	}
+	return before + content[end:], true
+}
+
+// writeRCFile replaces an rc file's contents, keeping its permissions.
+func writeRCFile(path, content string) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, []byte(content), mode)
+}
+
+// initShell writes mcc's block to the rc file of the given shell, or of the
+// user's shell when none is given. Running it again updates the block.
+func initShell(shell string) error {
+	if shell == "" {
+		var err error
+		if shell, err = detectShell(); err != nil {
+			return err
+		}
+	}
+	path, err := shellRCFile(shell)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	content := string(data)
+	rest, found := splitShellBlock(content)
+	block := shellBlock(shell)
+
+	if found && strings.Contains(content, block) {
+		fmt.Printf("✓ %s is already set up\n", shortenHome(path))
+		return nil
+	}
+	if found {
+		// Update the block where it is
+		start, end, _ := shellBlockBounds(content)
+		content = content[:start] + block + content[end:]
+	} else {
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		if content != "" {
+			content += "\n"
+		}
+		content += block
+	}
+	if err := writeRCFile(path, content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	verb := "Added mcc setup to"
+	if found {
+		verb = "Updated mcc setup in"
+	}
+	fmt.Printf("✓ %s %s\n", verb, shortenHome(path))
+	if strings.Contains(rest, "CLAUDE_CONFIG_DIR") {
+		fmt.Fprintf(os.Stderr, "⚠️  %s also sets CLAUDE_CONFIG_DIR outside mcc's block; you can remove that line.\n", shortenHome(path))
+	}
+	fmt.Printf("  Open a new terminal, or run: source %s\n", shortenHome(path))
+	return nil
+}
+
+// shellRCFiles returns the startup files of the usual shells that exist.
+func shellRCFiles() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	var files []string
+	for _, name := range []string{".zshrc", ".bashrc", ".bash_profile", ".profile", filepath.Join(".config", "fish", "config.fish")} {
+		if path := filepath.Join(home, name); fileExists(path) {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// shellBlockInstalled reports whether any rc file has mcc's block.
+func shellBlockInstalled() bool {
+	for _, shell := range supportedShells {
+		path, err := shellRCFile(shell)
+		if err != nil {
+			continue
+		}
+		if data, err := os.ReadFile(path); err == nil && strings.Contains(string(data), shellBlockStart) {
+			return true
+		}
+	}
+	return false
+}
+
+// uninstallShell removes mcc's block from every rc file and, with
+// restoreClaude, copies the default profile back to ~/.claude.
+func uninstallShell(restoreClaude bool) error {
+	removed := 0
+	seen := make(map[string]bool)
+	for _, shell := range supportedShells {
+		path, err := shellRCFile(shell)
+		if err != nil || seen[path] {
+			continue
+		}
+		seen[path] = true
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		rest, found := splitShellBlock(string(data))
+		if !found {
+			continue
+		}
+		if err := writeRCFile(path, rest); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		fmt.Printf("✓ Removed mcc setup from %s\n", shortenHome(path))
+		if strings.Contains(rest, "CLAUDE_CONFIG_DIR") {
+			fmt.Fprintf(os.Stderr, "⚠️  %s still sets CLAUDE_CONFIG_DIR outside mcc's block; remove that line by hand.\n", shortenHome(path))
+		}
+		removed++
+	}
+	if removed == 0 {
+		fmt.Println("No mcc setup found in your shell rc files")
+	}
+
+	if restoreClaude {
+		if err := restoreClaudeDir(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("Open a new terminal (or run: unset CLAUDE_CONFIG_DIR) so claude uses ~/.claude again.")
+	fmt.Printf("Your profiles are still in %s; delete it once you no longer need them.\n", shortenHome(getMccDir()))
+	return nil
+}
+
+// restoreClaudeDir copies the default profile to ~/.claude, keeping an
+// existing ~/.claude as a backup. Shared entries are copied as real files.
+func restoreClaudeDir() error {
+	src := filepath.Join(getProfilesDir(), defaultProfile)
+	if !profileExists(defaultProfile) {
+		return profileNotFound(defaultProfile)
+	}
+	dst := getClaudeDir()
+
+	if _, err := os.Lstat(dst); err == nil {
+		backup := dst + ".before-restore-" + time.Now().Format("20060102-150405")
+		if err := os.Rename(dst, backup); err != nil {
+			return fmt.Errorf("failed to move existing %s aside: %w", dst, err)
+		}
+		fmt.Printf("  Moved existing %s to %s\n", shortenHome(dst), shortenHome(backup))
+	}
+	if err := copyDir(src, dst); err != nil {
+		return fmt.Errorf("failed to copy profile '%s' to %s: %w", defaultProfile, dst, err)
+	}
+
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !isSharedLink(src, entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dst, entry.Name())
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		if err := copyEntry(filepath.Join(getSharedDir(), entry.Name()), path); err != nil {
+			return fmt.Errorf("failed to copy shared entry %s: %w", entry.Name(), err)
+		}
+	}
+	fmt.Printf("✓ Restored profile '%s' to %s\n", defaultProfile, shortenHome(dst))
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// useTempHome points HOME at an empty directory and makes mcc resolve its
+// layout again, so that a test works on files of its own.
+func useTempHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	for _, name := range []string{"MCC_HOME", "MCC_XDG", "XDG_CONFIG_HOME", "XDG_DATA_HOME", "ZDOTDIR"} {
+		t.Setenv(name, "")
+	}
+	layout = nil
+	t.Cleanup(func() { layout = nil })
+	return home
+}
+
+func TestSplitShellBlock(t *testing.T) {
+	block := shellBlockStart + "\nexport CLAUDE_CONFIG_DIR=\"/x\"\n" + shellBlockEnd + "\n"
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		found   bool
+	}{
+		{"empty", "", "", false},
+		{"no block", "alias ll='ls -l'\n", "alias ll='ls -l'\n", false},
+		{"only the block", block, "", true},
+		{"at the end", "alias ll='ls -l'\n\n" + block, "alias ll='ls -l'\n", true},
+		{"in the middle", "a\n\n" + block + "b\n", "a\n\nb\n", true},
+		{"no final newline", "a\n" + strings.TrimSuffix(block, "\n"), "a\n", true},
+		{"start without end", "a\n" + shellBlockStart + "\nb\n", "a\n" + shellBlockStart + "\nb\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := splitShellBlock(tt.content)
+			if got != tt.want || found != tt.found {
+				t.Errorf("splitShellBlock(%q) = %q, %v; want %q, %v", tt.content, got, found, tt.want, tt.found)
+			}
+		})
+	}
+}
+
+func TestInitShell(t *testing.T) {
+	home := useTempHome(t)
+	t.Setenv("ZDOTDIR", home)
+	rc := filepath.Join(home, ".zshrc")
+	if err := os.WriteFile(rc, []byte("alias ll='ls -l'\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	read := func() string {
+		t.Helper()
+		data, err := os.ReadFile(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	if err := initShell("zsh"); err != nil {
+		t.Fatal(err)
+	}
+	first := read()
+	if want := "alias ll='ls -l'\n\n" + shellBlock("zsh"); first != want {
+		t.Fatalf("after init:\n%s\nwant:\n%s", first, want)
+	}
+
+	// Running it again changes nothing
+	if err := initShell("zsh"); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(); got != first {
+		t.Fatalf("second init changed the file:\n%s", got)
+	}
+
+	// A new MCC_HOME updates the block in place
+	if err := setMccHome(filepath.Join(home, "elsewhere")); err != nil {
+		t.Fatal(err)
+	}
+	if err := initShell("zsh"); err != nil {
+		t.Fatal(err)
+	}
+	got := read()
+	if want := "alias ll='ls -l'\n\n" + shellBlock("zsh"); got != want {
+		t.Fatalf("after changing MCC_HOME:\n%s\nwant:\n%s", got, want)
+	}
+	if !strings.Contains(got, "MCC_HOME") || strings.Count(got, shellBlockStart) != 1 {
+		t.Fatalf("block not updated:\n%s", got)
+	}
+	info, err := os.Stat(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Fatalf("permissions not kept: %v", info.Mode())
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		in    string
+		shell string
+		fish  string
+	}{
+		{"/home/me/.mcc", `'/home/me/.mcc'`, `'/home/me/.mcc'`},
+		{"/home/josé", `'/home/josé'`, `'/home/josé'`},
+		{"/opt/$HOME/`id`", "'/opt/$HOME/`id`'", "'/opt/$HOME/`id`'"},
+		{"/it's", `'/it'\''s'`, `'/it\'s'`},
+		{`C:\x`, `'C:\x'`, `'C:\\x'`},
+	}
+	for _, tt := range tests {
+		if got := shellQuote(tt.in); got != tt.shell {
+			t.Errorf("shellQuote(%q) = %s, want %s", tt.in, got, tt.shell)
+		}
+		if got := fishQuote(tt.in); got != tt.fish {
+			t.Errorf("fishQuote(%q) = %s, want %s", tt.in, got, tt.fish)
+		}
+	}
+}
+
+func TestShellBlockInShell(t *testing.T) {
+	home := useTempHome(t)
+	mccHome := filepath.Join(home, "café $HOME `x` it's")
+	t.Setenv("MCC_HOME", mccHome)
+	layout = nil
+
+	for _, shell := range []string{"sh", "fish"} {
+		path, err := exec.LookPath(shell)
+		if err != nil {
+			continue
+		}
+		rc := filepath.Join(t.TempDir(), "rc")
+		if err := os.WriteFile(rc, []byte(shellBlock(shell)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command(path, "-c", `. "$1"; printf '%s\n%s' "$MCC_HOME" "$CLAUDE_CONFIG_DIR"`, "sh", rc)
+		if shell == "fish" {
+			cmd = exec.Command(path, "-c", `source $argv[1]; printf '%s\n%s' $MCC_HOME $CLAUDE_CONFIG_DIR`, rc)
+		}
+		cmd.Env = append(os.Environ(), "MCC_HOME=", "CLAUDE_CONFIG_DIR=")
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("%s: %v", shell, err)
+		}
+		if want := mccHome + "\n" + filepath.Join(mccHome, currentLinkName); string(out) != want {
+			t.Errorf("%s set:\n%s\nwant:\n%s", shell, out, want)
+		}
+	}
+}
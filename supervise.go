@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// claudeExitStatus is a non-zero exit status of a supervised claude, passed
+// on as mcc's own exit status.
+type claudeExitStatus int
+
+func (s claudeExitStatus) Error() string {
+	return fmt.Sprintf("claude exited with status %d", int(s))
+}
+
+// runClaude starts claude as a child process on the current terminal and
+// waits for it, returning its exit status.
+func runClaude(profilePath string, extraEnv []string, args []string) (int, error) {
+	claudePath, err := exec.LookPath("claude")
+	if err != nil {
+		return 0, fmt.Errorf("claude not found in PATH: %w", err)
+	}
+
+	cmd := exec.Command(claudePath, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), fmt.Sprintf("CLAUDE_CONFIG_DIR=%s", profilePath))
+	cmd.Env = append(cmd.Env, extraEnv...)
+
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start claude: %w", err)
+	}
+	recordLaunch(profilePath, cmd.Process.Pid)
+	stop := forwardSignals(cmd.Process)
+	err = cmd.Wait()
+	stop()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code := exitStatusOf(exitErr)
+		recordExit(profilePath, cmd.Process.Pid, code)
+		return code, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	recordExit(profilePath, cmd.Process.Pid, 0)
+	return 0, nil
+}
+
+// Shared so that input read ahead by one prompt isn't lost to the next.
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// confirm asks a yes/no question on the terminal, defaulting to yes.
+func confirm(question string) bool {
+	fmt.Printf("%s [Y/n] ", question)
+	answer, err := stdinReader.ReadString('\n')
+	if err != nil {
+		fmt.Println()
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "" || answer == "y" || answer == "yes"
+}
+
+// superviseClaude runs claude in a profile with mcc staying as its parent.
+// When the session ends on a usage limit or an authentication failure, it
+// offers to copy the session to the next available profile in priority order
+// and resume it there. claude's exit status is returned as a claudeExitStatus.
+func superviseClaude(name string, claudeArgs []string) error {
+	tried := make(map[string]bool)
+
+	for {
+		tried[name] = true
+		profilePath := filepath.Join(getProfilesDir(), name)
+		extraEnv, err := prepareLaunch(profilePath)
+		if err != nil {
+			return err
+		}
+
+		started := time.Now()
+		code, err := runClaude(profilePath, extraEnv, claudeArgs)
+		if err != nil {
+			return err
+		}
+
+		reason, limit, err := sessionFailure(name, started)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Could not check the session for failures: %v\n", err)
+		}
+		if limit != nil {
+			if err := recordRateLimit(name, limit); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  Could not record rate limit: %v\n", err)
+			}
+		}
+		if reason == "" {
+			return exitStatus(code)
+		}
+
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintf(os.Stderr, "⚠️  Profile '%s' %s.\n", name, reason)
+		next, err := nextAvailableProfile(tried)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "   No other profile to fail over to: %v\n", err)
+			return exitStatus(code)
+		}
+		if !confirm(fmt.Sprintf("   Continue the session in profile '%s'?", next)) {
+			return exitStatus(code)
+		}
+
+		claudeArgs = []string{"--continue"}
+		if session, err := latestSession(name, started); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Could not find the session: %v\n", err)
+		} else if session != nil {
+			if _, err := transferSession(session, next, false); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  Could not copy the session, continuing the latest one in '%s' instead: %v\n", next, err)
+			} else {
+				claudeArgs = []string{"--resume", session.ID}
+			}
+		}
+
+		if err := switchProfile(next, false); err != nil {
+			return err
+		}
+		name = next
+	}
+}
+
+// latestSession returns the most recently active session of a profile that
+// was written to since the given time, or nil when there is none.
+func latestSession(profile string, since time.Time) (*sessionInfo, error) {
+	sessions, err := collectSessions([]string{profile}, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 || sessions[0].Updated.Before(since.Add(-time.Second)) {
+		return nil, nil
+	}
+	return sessions[0], nil
+}
+
+// exitStatus turns claude's exit status into the error superviseClaude
+// returns.
+func exitStatus(code int) error {
+	if code == 0 {
+		return nil
+	}
+	return claudeExitStatus(code)
+}
@@ -0,0 +1,21 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+)
+
+// forwardSignals keeps Ctrl-C from stopping mcc; the console delivers it to
+// claude directly.
+func forwardSignals(child *os.Process) (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	return func() { signal.Stop(signals) }
+}
+
+func exitStatusOf(err *exec.ExitError) int {
+	return err.ExitCode()
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+const templatesDirName = "templates"
+
+// What 'mcc template save' takes from a profile. Login state, credentials
+// and the API key are never part of a template.
+var templateEntries = []string{
+	"settings.json",
+	"settings.local.json",
+	"CLAUDE.md",
+	"commands",
+	"agents",
+	"skills",
+}
+
+// profileTemplate seeds new profiles in place of the default profile. A
+// saved template is a directory under ~/.mcc/templates holding the entries
+// to copy and a .mcc-profile.json without a key; a manifest template names
+// its provider defaults and, optionally, a directory of files.
+type profileTemplate struct {
+	name   string
+	dir    string      // files copied into a new profile, or ""
+	meta   ProfileMeta // provider defaults; never holds an API key
+	source string      // where the template is defined
+}
+
+func getTemplatesDir() string {
+	return filepath.Join(getMccDir(), templatesDirName)
+}
+
+func validateTemplateName(name string) error {
+	if name == "" || strings.HasPrefix(name, ".") || strings.ContainsAny(name, "/\\:*?\"<>|") {
+		return usageError("invalid template name '%s'", name)
+	}
+	return nil
+}
+
+// fill sets the fields meta leaves empty from the template's defaults.
+func (t *profileTemplate) fill(meta *ProfileMeta) {
+	if meta.Provider == "" {
+		meta.Provider = t.meta.Provider
+	}
+	if meta.BaseURL == "" {
+		meta.BaseURL = t.meta.BaseURL
+	}
+	if meta.Model == "" {
+		meta.Model = t.meta.Model
+	}
+	if !meta.hasAPIKey() {
+		meta.KeyEnv = t.meta.KeyEnv
+		meta.KeyCommand = t.meta.KeyCommand
+	}
+	if len(meta.Tags) == 0 {
+		meta.Tags = t.meta.Tags
+	}
+	if meta.Color == "" {
+		meta.Color = t.meta.Color
+	}
+	if meta.Owner == "" {
+		meta.Owner = t.meta.Owner
+	}
+}
+
+// loadSavedTemplate reads a template from ~/.mcc/templates, returning nil
+// when there is none by that name.
+func loadSavedTemplate(name string) (*profileTemplate, error) {
+	dir := filepath.Join(getTemplatesDir(), name)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, nil
+	}
+	meta, err := loadProfileMeta(dir)
+	if err != nil {
+		return nil, err
+	}
+	meta.APIKey = ""
+	return &profileTemplate{name: name, dir: dir, meta: *meta, source: shortenHome(dir)}, nil
+}
+
+// findTemplate looks a template up in the manifest m, or the default
+// manifest when m is nil, and then among the saved templates. A template
+// the manifest defines wins over a saved one of the same name, which is
+// reported.
+func findTemplate(name string, m *manifest) (*profileTemplate, error) {
+	if err := validateTemplateName(name); err != nil {
+		return nil, err
+	}
+	if m == nil {
+		if _, err := os.Stat(getManifestPath()); err == nil {
+			if m, err = loadManifest(""); err != nil {
+				return nil, err
+			}
+		}
+	}
+	saved, err := loadSavedTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	if m != nil {
+		if t, ok := m.templates[name]; ok {
+			if saved != nil {
+				warnTemplateConflict(t, saved)
+			}
+			return t, nil
+		}
+	}
+	if saved != nil {
+		return saved, nil
+	}
+	return nil, fmt.Errorf("template '%s' does not exist; see mcc template list", name)
+}
+
+// Template names already warned about, so that each is reported once.
+var warnedTemplates = make(map[string]bool)
+
+func warnTemplateConflict(used, hidden *profileTemplate) {
+	if warnedTemplates[used.name] {
+		return
+	}
+	warnedTemplates[used.name] = true
+	fmt.Fprintf(os.Stderr, "⚠️  Template '%s' is defined in %s and saved in %s; using the one in %s\n", used.name, used.source, hidden.source, used.source)
+}
+
+// saveTemplate captures a profile's settings, commands, agents, provider
+// defaults, tags, color and owner as a template, leaving out credentials
+// and the API key.
+func saveTemplate(profile, name string, force bool, policy secretPolicy) error {
+	if err := validateTemplateName(name); err != nil {
+		return err
+	}
+	if !profileExists(profile) {
+		return profileNotFound(profile)
+	}
+	profilePath := filepath.Join(getProfilesDir(), profile)
+	meta, err := loadProfileMeta(profilePath)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(getTemplatesDir(), name)
+	if _, err := os.Stat(dir); err == nil && !force {
+		return fmt.Errorf("template '%s' already exists; use --force to replace it", name)
+	}
+	if err := os.MkdirAll(getTemplatesDir(), 0755); err != nil {
+		return err
+	}
+	// Built next to its final place and renamed into it when complete
+	tmp, err := os.MkdirTemp(getTemplatesDir(), "."+name+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	if err := os.Chmod(tmp, 0755); err != nil {
+		return err
+	}
+
+	var saved []string
+	for _, entry := range templateEntries {
+		src := filepath.Join(profilePath, entry)
+		if _, err := os.Lstat(src); err != nil {
+			continue
+		}
+		// Take the content of shared entries rather than the link
+		if resolved, err := filepath.EvalSymlinks(src); err == nil {
+			src = resolved
+		}
+		if _, _, err := syncSettings(src, filepath.Join(tmp, entry), policy); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", entry, err)
+		}
+		if _, err := os.Lstat(filepath.Join(tmp, entry)); err == nil {
+			saved = append(saved, entry)
+		}
+	}
+
+	defaults := ProfileMeta{
+		Provider:   meta.Provider,
+		BaseURL:    meta.BaseURL,
+		Model:      meta.Model,
+		KeyEnv:     meta.KeyEnv,
+		KeyCommand: meta.KeyCommand,
+		Tags:       meta.Tags,
+		Color:      meta.Color,
+		Owner:      meta.Owner,
+	}
+	if !defaults.isPlain() {
+		if err := saveProfileMeta(tmp, &defaults); err != nil {
+			return fmt.Errorf("failed to save template metadata: %w", err)
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Saved profile %s as template: %s\n", profile, name)
+	if len(saved) > 0 {
+		fmt.Printf("  Contains: %s\n", strings.Join(saved, ", "))
+	}
+	if meta.APIKey != "" {
+		notef("  The profile's API key was left out; profiles made from it need mcc set-key.\n")
+	}
+	fmt.Printf("  Use it with: mcc new <name> --template %s\n", name)
+	return nil
+}
+
+func deleteTemplate(name string) error {
+	if err := validateTemplateName(name); err != nil {
+		return err
+	}
+	dir := filepath.Join(getTemplatesDir(), name)
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("template '%s' does not exist in %s", name, shortenHome(getTemplatesDir()))
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Deleted template: %s\n", name)
+	return nil
+}
+
+func listTemplates() error {
+	templates := make(map[string]*profileTemplate)
+	entries, err := os.ReadDir(getTemplatesDir())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		t, err := loadSavedTemplate(entry.Name())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+			continue
+		}
+		templates[t.name] = t
+	}
+	// The manifest's templates win, as in findTemplate
+	if _, err := os.Stat(getManifestPath()); err == nil {
+		m, err := loadManifest("")
+		if err != nil {
+			return err
+		}
+		for name, t := range m.templates {
+			if saved, ok := templates[name]; ok {
+				warnTemplateConflict(t, saved)
+			}
+			templates[name] = t
+		}
+	}
+
+	if len(templates) == 0 {
+		fmt.Println("No templates. Use 'mcc template save <profile> <template>' to make one.")
+		return nil
+	}
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		t := templates[name]
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, t.meta.Provider, t.source)
+	}
+	w.Flush()
+	return nil
+}
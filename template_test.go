@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindTemplateOrder(t *testing.T) {
+	setupManifestHome(t, nil)
+	for name, provider := range map[string]string{"acme": "kimi", "solo": "claude"} {
+		dir := filepath.Join(getTemplatesDir(), name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := saveProfileMeta(dir, &ProfileMeta{Provider: provider}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src := "[templates.acme]\nprovider = \"claude\"\nmodel = \"opus\"\n\n[profiles.team]\ntemplate = \"acme\"\n"
+	if err := os.WriteFile(getManifestPath(), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := loadManifest("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.profiles[0].meta.Model; got != "opus" {
+		t.Errorf("manifest profile made from the saved template (model %q)", got)
+	}
+
+	for _, from := range []*manifest{m, nil} {
+		acme, err := findTemplate("acme", from)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if acme.meta.Model != "opus" || !strings.HasSuffix(acme.source, manifestFileName) {
+			t.Errorf("findTemplate(acme, %v) = %+v, want the manifest's", from != nil, acme)
+		}
+		solo, err := findTemplate("solo", from)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if solo.dir != filepath.Join(getTemplatesDir(), "solo") {
+			t.Errorf("findTemplate(solo) = %+v, want the saved one", solo)
+		}
+		if _, err := findTemplate("missing", from); err == nil {
+			t.Errorf("findTemplate(missing) succeeded")
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A small TOML reader and writer for the manifest, since mcc has no
+// dependencies. It covers tables, dotted and quoted keys, strings, integers,
+// booleans and arrays; not dates, floats, inline tables, multi-line strings
+// or arrays of tables.
+
+type tomlParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (p *tomlParser) errorf(format string, a ...any) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, a...))
+}
+
+func (p *tomlParser) peek() byte {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+// skipSpace skips blanks on the current line.
+func (p *tomlParser) skipSpace() {
+	for p.peek() == ' ' || p.peek() == '\t' {
+		p.pos++
+	}
+}
+
+// skipBlank skips blanks, comments and newlines.
+func (p *tomlParser) skipBlank() {
+	for p.pos < len(p.src) {
+		switch p.peek() {
+		case ' ', '\t', '\r':
+			p.pos++
+		case '\n':
+			p.pos++
+			p.line++
+		case '#':
+			for p.pos < len(p.src) && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// endLine expects only a comment before the end of the line.
+func (p *tomlParser) endLine() error {
+	p.skipSpace()
+	if p.peek() == '#' {
+		for p.pos < len(p.src) && p.peek() != '\n' {
+			p.pos++
+		}
+	}
+	if p.peek() == '\r' {
+		p.pos++
+	}
+	if p.pos < len(p.src) && p.peek() != '\n' {
+		return p.errorf("unexpected %q after value", p.src[p.pos:strings.IndexAny(p.src[p.pos:]+"\n", "\r\n")+p.pos])
+	}
+	return nil
+}
+
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+`)
+
+// key reads a possibly dotted key.
+func (p *tomlParser) key() ([]string, error) {
+	var parts []string
+	for {
+		p.skipSpace()
+		switch p.peek() {
+		case '"', '\'':
+			s, err := p.str()
+			if err != nil {
+				return nil, err
+			}
+			parts = append(parts, s)
+		default:
+			bare := bareKey.FindString(p.src[p.pos:])
+			if bare == "" {
+				return nil, p.errorf("expected a key")
+			}
+			p.pos += len(bare)
+			parts = append(parts, bare)
+		}
+		p.skipSpace()
+		if p.peek() != '.' {
+			return parts, nil
+		}
+		p.pos++
+	}
+}
+
+// str reads a basic ("...") or literal ('...') string.
+func (p *tomlParser) str() (string, error) {
+	quote := p.peek()
+	if strings.HasPrefix(p.src[p.pos:], strings.Repeat(string(quote), 3)) {
+		return "", p.errorf("multi-line strings are not supported")
+	}
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.pos >= len(p.src) || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.src[p.pos]
+		p.pos++
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case c == '\\' && quote == '"':
+			if p.pos >= len(p.src) {
+				return "", p.errorf("unterminated string")
+			}
+			esc := p.src[p.pos]
+			p.pos++
+			switch esc {
+			case 'b':
+				b.WriteByte('\b')
+			case 't':
+				b.WriteByte('\t')
+			case 'n':
+				b.WriteByte('\n')
+			case 'f':
+				b.WriteByte('\f')
+			case 'r':
+				b.WriteByte('\r')
+			case '"', '\\':
+				b.WriteByte(esc)
+			case 'u', 'U':
+				n := 4
+				if esc == 'U' {
+					n = 8
+				}
+				if p.pos+n > len(p.src) {
+					return "", p.errorf("invalid \\%c escape", esc)
+				}
+				code, err := strconv.ParseUint(p.src[p.pos:p.pos+n], 16, 32)
+				if err != nil || !utf8.ValidRune(rune(code)) {
+					return "", p.errorf("invalid \\%c escape", esc)
+				}
+				b.WriteRune(rune(code))
+				p.pos += n
+			default:
+				return "", p.errorf("invalid escape \\%c", esc)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+var tomlInteger = regexp.MustCompile(`^[+-]?[0-9][0-9_]*$`)
+
+func (p *tomlParser) value() (any, error) {
+	switch c := p.peek(); {
+	case c == '"' || c == '\'':
+		return p.str()
+	case c == '[':
+		p.pos++
+		var items []any
+		for {
+			p.skipBlank()
+			if p.peek() == ']' {
+				p.pos++
+				return items, nil
+			}
+			item, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			p.skipBlank()
+			switch p.peek() {
+			case ',':
+				p.pos++
+			case ']':
+			default:
+				return nil, p.errorf("expected , or ] in array")
+			}
+		}
+	}
+	// A bare value runs up to a blank, comma, bracket or comment
+	word := p.src[p.pos:]
+	if end := strings.IndexAny(word, " \t\r\n,]#"); end >= 0 {
+		word = word[:end]
+	}
+	switch {
+	case word == "":
+		return nil, p.errorf("expected a value")
+	case word == "true" || word == "false":
+		p.pos += len(word)
+		return word == "true", nil
+	case tomlInteger.MatchString(word):
+		n, err := strconv.ParseInt(strings.ReplaceAll(word, "_", ""), 10, 64)
+		if err != nil {
+			return nil, p.errorf("invalid integer %s", word)
+		}
+		p.pos += len(word)
+		return n, nil
+	}
+	return nil, p.errorf("unsupported value %s", word)
+}
+
+// table returns the table at path below root, creating it as needed.
+func tomlTable(root map[string]any, path []string) (map[string]any, error) {
+	table := root
+	for _, name := range path {
+		next, ok := table[name]
+		if !ok {
+			next = make(map[string]any)
+			table[name] = next
+		}
+		sub, ok := next.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("'%s' is not a table", name)
+		}
+		table = sub
+	}
+	return table, nil
+}
+
+// parseTOML decodes a document into nested maps holding strings, int64s,
+// bools and []any.
+func parseTOML(src string) (map[string]any, error) {
+	p := &tomlParser{src: src, line: 1}
+	root := make(map[string]any)
+	current := root
+	defined := make(map[string]bool)
+
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.src) {
+			return root, nil
+		}
+
+		if p.peek() == '[' {
+			p.pos++
+			if p.peek() == '[' {
+				return nil, p.errorf("arrays of tables are not supported")
+			}
+			path, err := p.key()
+			if err != nil {
+				return nil, err
+			}
+			if p.peek() != ']' {
+				return nil, p.errorf("expected ] after table name")
+			}
+			p.pos++
+			name := strings.Join(path, ".")
+			if defined[name] {
+				return nil, p.errorf("table [%s] is defined twice", name)
+			}
+			defined[name] = true
+			if current, err = tomlTable(root, path); err != nil {
+				return nil, p.errorf("%v", err)
+			}
+			if err := p.endLine(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		path, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != '=' {
+			return nil, p.errorf("expected = after %s", strings.Join(path, "."))
+		}
+		p.pos++
+		p.skipSpace()
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		table, err := tomlTable(current, path[:len(path)-1])
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		last := path[len(path)-1]
+		if _, ok := table[last]; ok {
+			return nil, p.errorf("%s is set twice", strings.Join(path, "."))
+		}
+		table[last] = value
+		if err := p.endLine(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// tomlKey writes a key, quoting it when it isn't bare.
+func tomlKey(key string) string {
+	if bareKey.FindString(key) == key && key != "" {
+		return key
+	}
+	return tomlString(key)
+}
+
+// tomlString writes a basic string.
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func tomlStrings(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = tomlString(item)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
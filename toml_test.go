@@ -0,0 +1,135 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want map[string]any
+	}{
+		{"empty", "", map[string]any{}},
+		{"comments and blank lines", "# top\n\n  # indented\n", map[string]any{}},
+		{"scalars", "s = \"x\"\nl = 'c:\\path'\nn = -1_000\nt = true\nf = false # off\n", map[string]any{
+			"s": "x", "l": `c:\path`, "n": int64(-1000), "t": true, "f": false,
+		}},
+		{"escapes", `s = "a\"b\\c\td\ne\u00e9\U0001F600"`, map[string]any{
+			"s": "a\"b\\c\td\neé😀",
+		}},
+		{"crlf", "a = 1\r\nb = 2\r\n", map[string]any{"a": int64(1), "b": int64(2)}},
+		{"tables", "[profiles.work]\nmodel = \"m\"\n[profiles.home]\nmodel = \"n\"\n", map[string]any{
+			"profiles": map[string]any{
+				"work": map[string]any{"model": "m"},
+				"home": map[string]any{"model": "n"},
+			},
+		}},
+		{"dotted and quoted keys", "a.b = 1\n\"c.d\" = 2\n[profiles.\"my work\"]\n'e'.f = 3\n", map[string]any{
+			"a":   map[string]any{"b": int64(1)},
+			"c.d": int64(2),
+			"profiles": map[string]any{
+				"my work": map[string]any{"e": map[string]any{"f": int64(3)}},
+			},
+		}},
+		{"spaces around dots", "[ a . b ]\nc . d = 1\n", map[string]any{
+			"a": map[string]any{"b": map[string]any{"c": map[string]any{"d": int64(1)}}},
+		}},
+		{"arrays", "a = []\nb = [1, 2]\nc = [\"x\", 'y',]\n", map[string]any{
+			"a": []any(nil), "b": []any{int64(1), int64(2)}, "c": []any{"x", "y"},
+		}},
+		{"multi-line array", "tags = [\n  \"a\", # first\n\n  \"b\",\n]\nnext = 1\n", map[string]any{
+			"tags": []any{"a", "b"}, "next": int64(1),
+		}},
+		{"nested arrays", "a = [[1], [\"x\", true]]", map[string]any{
+			"a": []any{[]any{int64(1)}, []any{"x", true}},
+		}},
+		{"no final newline", "a = \"x\" # c", map[string]any{"a": "x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTOML(tt.src)
+			if err != nil {
+				t.Fatalf("parseTOML(%q): %v", tt.src, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTOML(%q) = %#v, want %#v", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTOMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"float", "x = 1.5", "line 1: unsupported value 1.5"},
+		{"bool prefix", "x = trueish", "line 1: unsupported value trueish"},
+		{"bare word", "x = yes", "unsupported value yes"},
+		{"date", "x = 2026-01-02", "unsupported value 2026-01-02"},
+		{"garbage after string", "x = \"a\" b", `unexpected "b" after value`},
+		{"garbage after table", "[a] b", `unexpected "b" after value`},
+		{"two values", "x = 1 2", `unexpected "2" after value`},
+		{"missing value", "x =\n", "expected a value"},
+		{"missing equals", "x 1", "expected = after x"},
+		{"missing key", "= 1", "expected a key"},
+		{"duplicate key", "a = 1\nb = 2\na = 3\n", "line 3: a is set twice"},
+		{"duplicate dotted key", "a.b = 1\na.b = 2\n", "a.b is set twice"},
+		{"duplicate table", "[a]\nx = 1\n[b]\n[a]\n", "line 4: table [a] is defined twice"},
+		{"table over value", "a = 1\n[a]\n", "'a' is not a table"},
+		{"key over value", "a = 1\na.b = 2\n", "'a' is not a table"},
+		{"array of tables", "[[a]]", "arrays of tables are not supported"},
+		{"unclosed table", "[a\n", "expected ] after table name"},
+		{"unterminated string", "a = \"x\nb = 1", "line 1: unterminated string"},
+		{"multi-line string", `a = """x"""`, "multi-line strings are not supported"},
+		{"bad escape", `a = "\q"`, `invalid escape \q`},
+		{"short unicode escape", `a = "\u12"`, `invalid \u escape`},
+		{"surrogate escape", `a = "\uD800"`, `invalid \u escape`},
+		{"unclosed array", "a = [1, 2\nb = 3", "expected , or ] in array"},
+		{"line numbers in arrays", "a = [\n1,\n\n1.5]", "line 4: unsupported value 1.5"},
+		{"integer overflow", "a = 99999999999999999999", "invalid integer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseTOML(tt.src)
+			if err == nil {
+				t.Fatalf("parseTOML(%q) succeeded, want error %q", tt.src, tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseTOML(%q) error = %q, want %q", tt.src, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTOMLWriters(t *testing.T) {
+	values := []string{"", "plain", `quote " and \ backslash`, "tab\tnew\nline", "bell\a del\x7f", "ünï 😀", "'single'"}
+	var src strings.Builder
+	for i, value := range values {
+		src.WriteString(tomlKey(value) + " = " + tomlString(value) + "\n")
+		src.WriteString("list" + string(rune('a'+i)) + " = " + tomlStrings([]string{value, "x"}) + "\n")
+	}
+	doc, err := parseTOML(src.String())
+	if err != nil {
+		t.Fatalf("%v in:\n%s", err, src.String())
+	}
+	for i, value := range values {
+		if doc[value] != value {
+			t.Errorf("key and value %q came back as %#v", value, doc[value])
+		}
+		list := "list" + string(rune('a'+i))
+		if want := []any{value, "x"}; !reflect.DeepEqual(doc[list], want) {
+			t.Errorf("%s = %#v, want %#v", list, doc[list], want)
+		}
+	}
+
+	for key, want := range map[string]string{"work": "work", "my-work_2": "my-work_2", "a.b": `"a.b"`, "with space": `"with space"`, "": `""`} {
+		if got := tomlKey(key); got != want {
+			t.Errorf("tomlKey(%q) = %s, want %s", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const projectsDirName = "projects"
+
+// tokenUsage is the token accounting claude records on assistant messages.
+type tokenUsage struct {
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+}
+
+func (u *tokenUsage) add(other tokenUsage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CacheCreationInputTokens += other.CacheCreationInputTokens
+	u.CacheReadInputTokens += other.CacheReadInputTokens
+}
+
+// transcriptLine is the subset of a claude session transcript line mcc uses.
+type transcriptLine struct {
+	Type      string    `json:"type"`
+	SessionID string    `json:"sessionId"`
+	Timestamp time.Time `json:"timestamp"`
+	Cwd       string    `json:"cwd"`
+	RequestID string    `json:"requestId"`
+	APIError  bool      `json:"isApiErrorMessage"`
+	IsMeta    bool      `json:"isMeta"`
+	Message   struct {
+		ID      string          `json:"id"`
+		Role    string          `json:"role"`
+		Model   string          `json:"model"`
+		Content json.RawMessage `json:"content"`
+		Usage   *tokenUsage     `json:"usage"`
+	} `json:"message"`
+}
+
+// transcriptEntry is one user or assistant message from a transcript.
+type transcriptEntry struct {
+	profile   string
+	project   string
+	sessionID string
+	timestamp time.Time
+	role      string
+	model     string
+	usage     tokenUsage
+	apiError  bool
+	meta      bool
+	content   json.RawMessage
+	// Transcript file the message was read from
+	path string
+}
+
+// text returns the plain text of the message, joining text blocks.
+func (e transcriptEntry) text() string {
+	var plain string
+	if json.Unmarshal(e.content, &plain) == nil {
+		return plain
+	}
+	var blocks []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if json.Unmarshal(e.content, &blocks) != nil {
+		return ""
+	}
+	var parts []string
+	for _, block := range blocks {
+		if block.Type == "text" && block.Text != "" {
+			parts = append(parts, block.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// getProfileProjectsDir returns where claude keeps a profile's transcripts.
+func getProfileProjectsDir(profile string) string {
+	return filepath.Join(getProfilesDir(), profile, projectsDirName)
+}
+
+// scanTranscripts calls fn for every message in the profile's transcripts
+// at or after since. Assistant messages that claude writes several times
+// while streaming are reported once.
+func scanTranscripts(profile string, since time.Time, fn func(entry transcriptEntry)) error {
+	return scanTranscriptsMatching(profile, since, []byte(`"message"`), fn)
+}
+
+// scanTranscriptsMatching is scanTranscripts limited to lines containing
+// marker, which avoids decoding lines the caller has no use for.
+func scanTranscriptsMatching(profile string, since time.Time, marker []byte, fn func(entry transcriptEntry)) error {
+	root := getProfileProjectsDir(profile)
+	seen := make(map[string]bool)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".jsonl" {
+			return nil
+		}
+		// A file last written before since holds nothing newer. File times
+		// come from a coarse clock, so allow them to lag a little.
+		if info, err := d.Info(); err == nil && info.ModTime().Before(since.Add(-time.Second)) {
+			return nil
+		}
+
+		// projects/<encoded-path>/<session>.jsonl
+		rel, _ := filepath.Rel(root, path)
+		dirProject := strings.SplitN(filepath.ToSlash(rel), "/", 2)[0]
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		return forEachLine(f, func(raw []byte) error {
+			if !bytes.Contains(raw, marker) {
+				return nil
+			}
+			var line transcriptLine
+			if json.Unmarshal(raw, &line) != nil {
+				// Partially written last line or an unknown format
+				return nil
+			}
+			if line.Type != "user" && line.Type != "assistant" {
+				return nil
+			}
+			if line.Timestamp.Before(since) {
+				return nil
+			}
+			if line.Type == "assistant" && line.Message.ID != "" {
+				key := line.Message.ID + ":" + line.RequestID
+				if seen[key] {
+					return nil
+				}
+				seen[key] = true
+			}
+
+			entry := transcriptEntry{
+				profile:   profile,
+				project:   line.Cwd,
+				sessionID: line.SessionID,
+				timestamp: line.Timestamp,
+				role:      line.Type,
+				model:     line.Message.Model,
+				apiError:  line.APIError,
+				meta:      line.IsMeta,
+				content:   line.Message.Content,
+				path:      path,
+			}
+			if entry.project == "" {
+				entry.project = dirProject
+			}
+			if entry.sessionID == "" {
+				entry.sessionID = strings.TrimSuffix(filepath.Base(path), ".jsonl")
+			}
+			if line.Message.Usage != nil {
+				entry.usage = *line.Message.Usage
+			}
+			fn(entry)
+			return nil
+		})
+	})
+	return err
+}
+
+// parseSince turns "7d", "12h", "2w" or a YYYY-MM-DD date into a start time.
+// An empty value means no limit.
+func parseSince(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+
+	units := map[byte]time.Duration{
+		'h': time.Hour,
+		'd': 24 * time.Hour,
+		'w': 7 * 24 * time.Hour,
+	}
+	if len(value) >= 2 {
+		if unit, ok := units[value[len(value)-1]]; ok {
+			if n, err := strconv.Atoi(value[:len(value)-1]); err == nil && n >= 0 {
+				return time.Now().Add(-time.Duration(n) * unit), nil
+			}
+		}
+	}
+	return time.Time{}, usageError("invalid --since value '%s' (use e.g. 7d, 12h, 2w or 2006-01-02)", value)
+}
+
+// formatTokens abbreviates a token count, e.g. 1234567 → 1.2M.
+func formatTokens(n int64) string {
+	switch {
+	case n >= 1_000_000_000:
+		return fmt.Sprintf("%.1fB", float64(n)/1e9)
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1e6)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fK", float64(n)/1e3)
+	}
+	return strconv.FormatInt(n, 10)
+}
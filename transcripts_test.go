@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		value string
+		want  time.Time // zero for no limit
+		ago   time.Duration
+	}{
+		{value: ""},
+		{value: "2026-01-02", want: time.Date(2026, 1, 2, 0, 0, 0, 0, time.Local)},
+		{value: "12h", ago: 12 * time.Hour},
+		{value: "7d", ago: 7 * 24 * time.Hour},
+		{value: "2w", ago: 14 * 24 * time.Hour},
+		{value: "0d"},
+	}
+	for _, tt := range tests {
+		got, err := parseSince(tt.value)
+		if err != nil {
+			t.Errorf("parseSince(%q): %v", tt.value, err)
+			continue
+		}
+		want := tt.want
+		if tt.value != "" && want.IsZero() {
+			want = now.Add(-tt.ago)
+		}
+		if d := got.Sub(want); d < -time.Second || d > time.Second {
+			t.Errorf("parseSince(%q) = %v, want %v", tt.value, got, want)
+		}
+	}
+
+	for _, value := range []string{"7", "d", "7m", "-1d", "1.5d", "2026-13-01", "yesterday"} {
+		_, err := parseSince(value)
+		if err == nil {
+			t.Errorf("parseSince(%q) succeeded, want an error", value)
+		} else if exitCodeOf(err) != exitUsage {
+			t.Errorf("parseSince(%q) error %v isn't a usage error", value, err)
+		}
+	}
+}
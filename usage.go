@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// usageStats aggregates transcript messages for a profile or project.
+type usageStats struct {
+	Name     string `json:"name"`
+	Sessions int    `json:"sessions"`
+	Messages int    `json:"messages"`
+	tokenUsage
+	Projects []*usageStats `json:"projects,omitempty"`
+
+	sessions map[string]bool
+	projects map[string]*usageStats
+}
+
+func newUsageStats(name string) *usageStats {
+	return &usageStats{
+		Name:     name,
+		sessions: make(map[string]bool),
+		projects: make(map[string]*usageStats),
+	}
+}
+
+func (s *usageStats) add(entry transcriptEntry) {
+	s.Messages++
+	s.tokenUsage.add(entry.usage)
+	s.sessions[entry.sessionID] = true
+	s.Sessions = len(s.sessions)
+}
+
+// collectUsage aggregates the transcripts of a profile since the given time.
+func collectUsage(profile string, since time.Time) (*usageStats, error) {
+	stats := newUsageStats(profile)
+	err := scanTranscripts(profile, since, func(entry transcriptEntry) {
+		stats.add(entry)
+		project, ok := stats.projects[entry.project]
+		if !ok {
+			project = newUsageStats(entry.project)
+			stats.projects[entry.project] = project
+		}
+		project.add(entry)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcripts of profile '%s': %w", profile, err)
+	}
+
+	for _, project := range stats.projects {
+		stats.Projects = append(stats.Projects, project)
+	}
+	sort.Slice(stats.Projects, func(i, j int) bool {
+		a, b := stats.Projects[i], stats.Projects[j]
+		if a.OutputTokens != b.OutputTokens {
+			return a.OutputTokens > b.OutputTokens
+		}
+		return a.Name < b.Name
+	})
+	return stats, nil
+}
+
+func showUsage(profile string, sinceValue string, asJSON bool) error {
+	since, err := parseSince(sinceValue)
+	if err != nil {
+		return err
+	}
+
+	var profiles []string
+	if profile != "" {
+		profiles, err = resolveProfiles([]string{profile})
+	} else {
+		profiles, err = listProfiles()
+	}
+	if err != nil {
+		return err
+	}
+
+	var all []*usageStats
+	for _, p := range profiles {
+		stats, err := collectUsage(p, since)
+		if err != nil {
+			return err
+		}
+		all = append(all, stats)
+	}
+
+	if asJSON {
+		out := struct {
+			Since    *time.Time    `json:"since,omitempty"`
+			Profiles []*usageStats `json:"profiles"`
+		}{Profiles: all}
+		if !since.IsZero() {
+			out.Since = &since
+		}
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if since.IsZero() {
+		fmt.Println("Usage from all transcripts")
+	} else {
+		fmt.Printf("Usage since %s\n", since.Format("2006-01-02 15:04"))
+	}
+	fmt.Println()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROFILE / PROJECT\tSESSIONS\tMESSAGES\tINPUT\tOUTPUT\tCACHE WRITE\tCACHE READ")
+	row := func(name string, s *usageStats) {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\t%s\n", name, s.Sessions, s.Messages,
+			formatTokens(s.InputTokens), formatTokens(s.OutputTokens),
+			formatTokens(s.CacheCreationInputTokens), formatTokens(s.CacheReadInputTokens))
+	}
+	for _, stats := range all {
+		row(stats.Name, stats)
+		for _, project := range stats.Projects {
+			row("  "+project.Name, project)
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// How long to wait for a burst of changes to settle before syncing.
+const syncDebounce = 500 * time.Millisecond
+
+// treeWatcher reports changed paths below a directory, relative to it.
+// Platform files provide newTreeWatcher.
+type treeWatcher struct {
+	changes chan string
+	errors  chan error
+	stop    func()
+}
+
+// syncPathExcluded applies the syncSettings exclusion rules to every
+// component of a relative path.
+func syncPathExcluded(rel string) bool {
+	for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+		if isSyncSkipDir(part) || isSyncExcluded(part) {
+			return true
+		}
+	}
+	return false
+}
+
+// propagateChange copies a changed path from srcDir into each target profile,
+// or removes it there when it no longer exists in srcDir.
+func propagateChange(srcDir string, rel string, targets []string, policy secretPolicy) {
+	src := filepath.Join(srcDir, rel)
+	_, statErr := os.Lstat(src)
+	removed := os.IsNotExist(statErr)
+
+	var done []string
+	for _, target := range targets {
+		dst := filepath.Join(getProfilesDir(), target, rel)
+		var err error
+		copied := 0
+		if removed {
+			err = os.RemoveAll(dst)
+		} else if err = os.MkdirAll(filepath.Dir(dst), 0755); err == nil {
+			copied, _, err = syncSettings(src, dst, policy)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "⚠️  %s: %s: %v\n", target, rel, err)
+			continue
+		}
+		if removed || copied > 0 {
+			done = append(done, target)
+		}
+	}
+
+	if len(done) == 0 {
+		return
+	}
+	op := "~"
+	if removed {
+		op = "-"
+	}
+	fmt.Printf("[%s] %s %s → %s\n", time.Now().Format("15:04:05"), op, rel, strings.Join(done, ", "))
+}
+
+// watchSync keeps the target profiles in sync with the sync source until
+// interrupted, using the same exclusion rules as syncSettings.
+func watchSync(targets []string, from string, policy secretPolicy) error {
+	srcDir, label, err := syncSource(from)
+	if err != nil {
+		return err
+	}
+	for _, target := range targets {
+		if target == from {
+			return fmt.Errorf("cannot sync profile '%s' onto itself", target)
+		}
+	}
+
+	watcher, err := newTreeWatcher(srcDir, isSyncSkipDir)
+	if err != nil {
+		return fmt.Errorf("failed to watch %s: %w", label, err)
+	}
+	defer watcher.stop()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	fmt.Printf("Watching %s → %s (Ctrl-C to stop)\n", label, strings.Join(targets, ", "))
+
+	pending := make(map[string]bool)
+	flush := func() {
+		// Other mcc commands can run between passes
+		unlock, err := lockState()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+			return
+		}
+		defer unlock()
+		paths := make([]string, 0, len(pending))
+		for rel := range pending {
+			paths = append(paths, rel)
+		}
+		sort.Strings(paths)
+		for _, rel := range paths {
+			propagateChange(srcDir, rel, targets, policy)
+		}
+		pending = make(map[string]bool)
+	}
+
+	var settle <-chan time.Time
+	for {
+		select {
+		case rel, ok := <-watcher.changes:
+			if !ok {
+				flush()
+				return fmt.Errorf("stopped watching %s", label)
+			}
+			if syncPathExcluded(rel) {
+				continue
+			}
+			pending[rel] = true
+			settle = time.After(syncDebounce)
+		case err := <-watcher.errors:
+			flush()
+			return fmt.Errorf("watching %s failed: %w", label, err)
+		case <-settle:
+			flush()
+			settle = nil
+		case <-sigCh:
+			flush()
+			fmt.Println("\nStopped watching")
+			return nil
+		}
+	}
+}
@@ -0,0 +1,111 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_ATTRIB |
+	syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// newTreeWatcher watches root and all directories below it with inotify,
+// adding watches for directories created later.
+func newTreeWatcher(root string, skipDir func(name string) bool) (*treeWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	file := os.NewFile(uintptr(fd), "inotify")
+
+	// Only touched by the reader goroutine once it is started
+	dirs := make(map[int32]string)
+	addTree := func(rel string) error {
+		top := filepath.Join(root, rel)
+		return filepath.WalkDir(top, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				// The directory may already be gone again
+				return nil
+			}
+			if !d.IsDir() {
+				return nil
+			}
+			if path != root && skipDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			wd, err := syscall.InotifyAddWatch(fd, path, inotifyMask)
+			if err != nil {
+				return err
+			}
+			relPath, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			dirs[int32(wd)] = relPath
+			return nil
+		})
+	}
+
+	if err := addTree("."); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	w := &treeWatcher{
+		changes: make(chan string, 64),
+		errors:  make(chan error, 1),
+		stop:    func() { file.Close() },
+	}
+
+	go func() {
+		defer close(w.changes)
+		buf := make([]byte, 64*1024)
+		for {
+			n, err := file.Read(buf)
+			if err != nil {
+				if !errors.Is(err, os.ErrClosed) {
+					w.errors <- err
+				}
+				return
+			}
+
+			for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+				event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+				nameStart := offset + syscall.SizeofInotifyEvent
+				name := strings.TrimRight(string(buf[nameStart:nameStart+int(event.Len)]), "\x00")
+				offset = nameStart + int(event.Len)
+
+				if event.Mask&syscall.IN_Q_OVERFLOW != 0 {
+					// Events were lost, resync everything
+					w.changes <- "."
+					continue
+				}
+				if event.Mask&syscall.IN_IGNORED != 0 {
+					delete(dirs, event.Wd)
+					continue
+				}
+				dir, ok := dirs[event.Wd]
+				if !ok || name == "" {
+					continue
+				}
+
+				rel := filepath.Join(dir, name)
+				if event.Mask&syscall.IN_ISDIR != 0 && event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 && !skipDir(name) {
+					if err := addTree(rel); err != nil {
+						w.errors <- err
+						return
+					}
+				}
+				w.changes <- rel
+			}
+		}
+	}()
+
+	return w, nil
+}
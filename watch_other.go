@@ -0,0 +1,89 @@
+//go:build !linux
+
+package main
+
+import (
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+const watchPollInterval = time.Second
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// newTreeWatcher polls root for changes on platforms without inotify.
+func newTreeWatcher(root string, skipDir func(name string) bool) (*treeWatcher, error) {
+	scan := func() (map[string]fileStamp, error) {
+		stamps := make(map[string]fileStamp)
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if path != root && skipDir(d.Name()) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			stamps[rel] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+			return nil
+		})
+		return stamps, err
+	}
+
+	prev, err := scan()
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	w := &treeWatcher{
+		changes: make(chan string, 64),
+		errors:  make(chan error, 1),
+		stop:    func() { close(done) },
+	}
+
+	go func() {
+		defer close(w.changes)
+		ticker := time.NewTicker(watchPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			cur, err := scan()
+			if err != nil {
+				w.errors <- err
+				return
+			}
+			for rel, stamp := range cur {
+				if old, ok := prev[rel]; !ok || old != stamp {
+					w.changes <- rel
+				}
+			}
+			for rel := range prev {
+				if _, ok := cur[rel]; !ok {
+					w.changes <- rel
+				}
+			}
+			prev = cur
+		}
+	}()
+
+	return w, nil
+}